@@ -50,6 +50,38 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:      "pause-feed",
+				Usage:     "pauses a feed, so it is no longer checked for new items",
+				ArgsUsage: "feed-name",
+				Action: func(cCtx *cli.Context) error {
+					name := cCtx.Args().First()
+					if name == "" {
+						return errors.New("no feed specified")
+					}
+					if err := client.PauseFeed(name); err != nil {
+						return err
+					}
+					fmt.Printf("Paused feed \"%s\"\n", name)
+					return nil
+				},
+			},
+			{
+				Name:      "pause-webhook",
+				Usage:     "pauses a webhook, so new messages are queued but not sent",
+				ArgsUsage: "webhook-name",
+				Action: func(cCtx *cli.Context) error {
+					name := cCtx.Args().First()
+					if name == "" {
+						return errors.New("no webhook specified")
+					}
+					if err := client.PauseWebhook(name); err != nil {
+						return err
+					}
+					fmt.Printf("Paused webhook \"%s\"\n", name)
+					return nil
+				},
+			},
 			{
 				Name:      "ping",
 				Usage:     "send a test message to a webhook",
@@ -82,6 +114,31 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:      "preview",
+				Usage:     "shows how items of a feed would be posted, without posting them",
+				ArgsUsage: "url",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:    "items",
+						Aliases: []string{"n"},
+						Usage:   "number of items to preview",
+						Value:   3,
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					url := cCtx.Args().First()
+					if url == "" {
+						return errors.New("no url specified")
+					}
+					text, err := client.Preview(url, cCtx.Int("items"))
+					if err != nil {
+						return err
+					}
+					fmt.Println(text)
+					return nil
+				},
+			},
 			{
 				Name:  "restart",
 				Usage: "restarts the service",
@@ -93,6 +150,38 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:      "resume-feed",
+				Usage:     "resumes a paused feed",
+				ArgsUsage: "feed-name",
+				Action: func(cCtx *cli.Context) error {
+					name := cCtx.Args().First()
+					if name == "" {
+						return errors.New("no feed specified")
+					}
+					if err := client.ResumeFeed(name); err != nil {
+						return err
+					}
+					fmt.Printf("Resumed feed \"%s\"\n", name)
+					return nil
+				},
+			},
+			{
+				Name:      "resume-webhook",
+				Usage:     "resumes a paused webhook and sends all queued messages",
+				ArgsUsage: "webhook-name",
+				Action: func(cCtx *cli.Context) error {
+					name := cCtx.Args().First()
+					if name == "" {
+						return errors.New("no webhook specified")
+					}
+					if err := client.ResumeWebhook(name); err != nil {
+						return err
+					}
+					fmt.Printf("Resumed webhook \"%s\"\n", name)
+					return nil
+				},
+			},
 			{
 				Name:  "stats",
 				Usage: "show current statistics",
@@ -25,6 +25,7 @@ import (
 const (
 	configFilename  = "config.toml"
 	dbFileName      = "feedhook.db"
+	dbFileNameDry   = "feedhook-dryrun.db"
 	boltOpenTimeout = 5 * time.Second
 	portRPC         = 2233
 )
@@ -44,6 +45,8 @@ func main() {
 	portFlag := flag.Int("port", portRPC, "port for RPC service")
 	versionFlag := flag.Bool("v", false, "show version")
 	offlineFlag := flag.Bool("offline", false, "run RPC service only")
+	dryRunFlag := flag.Bool("dry-run", false, "run without sending messages to Discord. Uses a separate database")
+	dryRunFileFlag := flag.String("dry-run-file", "", "path to JSONL file for recording messages in dry-run mode. Messages are logged when empty")
 	flag.Usage = myUsage
 	flag.Parse()
 	if *versionFlag {
@@ -57,7 +60,14 @@ func main() {
 		os.Exit(1)
 	}
 	slog.SetLogLoggerLevel(cfg.App.LoggerLevel())
-	p := filepath.Join(*dbPathFlag, dbFileName)
+	cfg.App.DryRun = *dryRunFlag
+	cfg.App.DryRunFile = *dryRunFileFlag
+	fn := dbFileName
+	if cfg.App.DryRun {
+		fn = dbFileNameDry
+		slog.Warn("Running in dry-run mode. Messages will not be sent to Discord")
+	}
+	p := filepath.Join(*dbPathFlag, fn)
 	db, err := bolt.Open(p, 0600, &bolt.Options{Timeout: boltOpenTimeout})
 	if err != nil {
 		slog.Error("Failed to open DB", "error", err)
@@ -71,13 +81,28 @@ func main() {
 	}
 
 	// start the dispatcher
-	d := dispatcher.New(st, cfg, realtime{})
+	d, err := dispatcher.New(st, cfg, realtime{})
+	if err != nil {
+		slog.Error("Failed to create dispatcher", "error", err)
+		os.Exit(1)
+	}
 	if !*offlineFlag {
 		if err := d.Start(); err != nil {
 			slog.Error("Failed to start dispatcher", "error", err)
 			os.Exit(1)
 		}
 		defer d.Stop()
+
+		// start WebSub callback endpoint
+		if h := d.WebSubHandler(); h != nil {
+			go func() {
+				slog.Info("WebSub callback endpoint running", "address", cfg.App.WebSub.Listen)
+				err := http.ListenAndServe(cfg.App.WebSub.Listen, h)
+				slog.Error("WebSub callback endpoint aborted", "error", err)
+			}()
+		} else if cfg.App.DryRun && cfg.App.WebSub.IsEnabled() {
+			slog.Warn("WebSub is disabled in dry-run mode. Feeds are polled.")
+		}
 	}
 
 	// start RPC service
@@ -0,0 +1,112 @@
+// Package cachedfetch fetches resources over HTTP and caches the results derived from them.
+package cachedfetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	cacheMaxEntries = 1000
+	cacheTimeout    = 24 * time.Hour
+	failureTimeout  = time.Hour // how long failed fetches are cached
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type cacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// Fetcher fetches resources and derives values from them with a parse function.
+// Values are cached by URL. Failures are cached too, so that broken URLs are not
+// requested again on every call.
+// A Fetcher is safe for concurrent use by multiple goroutines.
+type Fetcher[T any] struct {
+	client    *http.Client
+	clock     Clock
+	parse     func(resp *http.Response) (T, error)
+	timeout   time.Duration
+	userAgent string
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry[T]
+}
+
+// New returns a new Fetcher, which derives values from successful responses with parse.
+// Requests are aborted after timeout.
+func New[T any](client *http.Client, clock Clock, timeout time.Duration, userAgent string, parse func(resp *http.Response) (T, error)) *Fetcher[T] {
+	f := &Fetcher[T]{
+		client:    client,
+		clock:     clock,
+		parse:     parse,
+		timeout:   timeout,
+		userAgent: userAgent,
+		cache:     make(map[string]cacheEntry[T]),
+	}
+	return f
+}
+
+// Get returns the value for a URL.
+// Returns an error only when the URL was fetched and failed.
+// Until a failure expires from the cache the zero value is returned without error.
+func (f *Fetcher[T]) Get(rawURL string) (T, error) {
+	now := f.clock.Now()
+	f.mu.Lock()
+	e, ok := f.cache[rawURL]
+	f.mu.Unlock()
+	if ok && now.Before(e.expiresAt) {
+		return e.value, nil
+	}
+	v, err := f.fetch(rawURL)
+	timeout := cacheTimeout
+	if err != nil {
+		timeout = failureTimeout
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.cache) >= cacheMaxEntries {
+		for k, v := range f.cache {
+			if !now.Before(v.expiresAt) {
+				delete(f.cache, k)
+			}
+		}
+		if len(f.cache) >= cacheMaxEntries {
+			clear(f.cache)
+		}
+	}
+	f.cache[rawURL] = cacheEntry[T]{value: v, expiresAt: now.Add(timeout)}
+	return v, err
+}
+
+func (f *Fetcher[T]) fetch(rawURL string) (T, error) {
+	var zero T
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return zero, err
+	}
+	if f.userAgent != "" {
+		req.Header.Set("User-Agent", f.userAgent)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return zero, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return zero, fmt.Errorf("fetch: %s", resp.Status)
+	}
+	v, err := f.parse(resp)
+	if err != nil {
+		return zero, err
+	}
+	return v, nil
+}
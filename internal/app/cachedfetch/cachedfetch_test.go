@@ -0,0 +1,78 @@
+package cachedfetch_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/cachedfetch"
+)
+
+type fakeTime struct {
+	now time.Time
+}
+
+func (rt fakeTime) Now() time.Time {
+	return rt.now
+}
+
+func parseBody(resp *http.Response) (string, error) {
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func TestFetcher(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+	t.Run("should return parsed value", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://www.example.com/a", func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "agent", req.Header.Get("User-Agent"))
+			return httpmock.NewStringResponse(200, "alpha"), nil
+		})
+		f := cachedfetch.New(http.DefaultClient, fakeTime{now: now}, 5*time.Second, "agent", parseBody)
+		got, err := f.Get("https://www.example.com/a")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "alpha", got)
+		}
+	})
+	t.Run("should cache values until they expire", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://www.example.com/a", httpmock.NewStringResponder(200, "alpha"))
+		clock := &fakeTime{now: now}
+		f := cachedfetch.New(http.DefaultClient, clock, 5*time.Second, "agent", parseBody)
+		for range 2 {
+			if _, err := f.Get("https://www.example.com/a"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+		clock.now = now.Add(25 * time.Hour)
+		if _, err := f.Get("https://www.example.com/a"); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	})
+	t.Run("should cache failures", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://www.example.com/a", httpmock.NewStringResponder(404, ""))
+		clock := &fakeTime{now: now}
+		f := cachedfetch.New(http.DefaultClient, clock, 5*time.Second, "agent", parseBody)
+		_, err := f.Get("https://www.example.com/a")
+		assert.Error(t, err)
+		got, err := f.Get("https://www.example.com/a")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "", got)
+		}
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+		clock.now = now.Add(2 * time.Hour)
+		_, err = f.Get("https://www.example.com/a")
+		assert.Error(t, err)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	})
+}
@@ -5,16 +5,34 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/BurntSushi/toml"
+	"github.com/andybalholm/cascadia"
 )
 
 const (
-	timeoutDefault  = 30
-	oldestDefault   = 7200
-	tickerDefault   = 30
-	logLevelDefault = slog.LevelInfo
+	backoffDefault           = 3600
+	timeoutDefault           = 30
+	oldestDefault            = 7200
+	tickerDefault            = 30
+	logLevelDefault          = slog.LevelInfo
+	maxFetchesDefault        = 10
+	maxHostFetchesDefault    = 2
+	websubFallbackDefault    = 3600
+	websubLeaseDefault       = 86400 * 7
+	dedupWindowDefault       = 86400
+	maxItemsDefault          = 1000
+	ogImageTimeoutDefault    = 5
+	translatorTimeoutDefault = 10
+	maxMessagesDefault       = 3
+	forumTagsMax             = 5
+	usernameMaxLength        = 80
 )
 
 type Config struct {
@@ -23,6 +41,23 @@ type Config struct {
 	Webhooks []ConfigWebhook
 }
 
+// FeedRetention returns the effective retention for a feed.
+// Values defined for the feed override the global values.
+// Zero values are inherited from the global retention, so a feed can not disable a global limit.
+func (mc *Config) FeedRetention(cf ConfigFeed) ConfigRetention {
+	r := mc.App.Retention
+	if cf.Retention == nil {
+		return r
+	}
+	if cf.Retention.MaxAge != 0 {
+		r.MaxAge = cf.Retention.MaxAge
+	}
+	if cf.Retention.MaxItems != 0 {
+		r.MaxItems = cf.Retention.MaxItems
+	}
+	return r
+}
+
 func (mc *Config) EnabledFeeds() []ConfigFeed {
 	feeds := make([]ConfigFeed, 0)
 	for _, f := range mc.Feeds {
@@ -35,12 +70,156 @@ func (mc *Config) EnabledFeeds() []ConfigFeed {
 }
 
 type ConfigApp struct {
+	BackoffMax       int    `toml:"backoff_max"`
 	BrandingDisabled bool   `toml:"branding_disabled"`
 	DBPath           string `toml:"db_path"`
+	FetchJitter      int    `toml:"fetch_jitter"`
 	LogLevel         string `toml:"loglevel"`
+	MaxFetches       int    `toml:"max_concurrent_fetches"`
+	MaxHostFetches   int    `toml:"max_concurrent_fetches_per_host"`
+	OGImageTimeout   int    `toml:"og_image_timeout"`
 	Oldest           int    `toml:"oldest"`
 	Ticker           int    `toml:"ticker"`
 	Timeout          int    `toml:"timeout"`
+	UserAgent        string `toml:"user_agent"`
+
+	HTTP       ConfigHTTP       `toml:"http"`       // global settings for outbound HTTP connections
+	Retention  ConfigRetention  `toml:"retention"`  // how long processed items are remembered
+	Translator ConfigTranslator `toml:"translator"` // service for translating items
+	WebSub     ConfigWebSub     `toml:"websub"`     // push subscriptions for feeds
+
+	// Dry-run mode is set by command line flags only
+	DryRun     bool   `toml:"-"` // messages are recorded instead of being sent
+	DryRunFile string `toml:"-"` // file for recording messages. Messages are logged when empty.
+}
+
+// ConfigRetention defines how long processed items of a feed are remembered.
+// Forgotten items are sent again, when they are still in the feed.
+type ConfigRetention struct {
+	MaxAge   int `toml:"max_age"`   // max age of items in seconds by publishing date. Disabled when 0.
+	MaxItems int `toml:"max_items"` // max number of items. Disabled when 0.
+}
+
+func (c ConfigRetention) validate(oldest int) error {
+	if c.MaxAge < 0 || c.MaxItems < 0 {
+		return fmt.Errorf("values can not be negative")
+	}
+	if c.MaxAge > 0 && oldest > 0 && c.MaxAge < oldest {
+		return fmt.Errorf("max_age must not be less then oldest")
+	}
+	return nil
+}
+
+// ConfigWebSub defines settings for WebSub push subscriptions.
+type ConfigWebSub struct {
+	CallbackURL  string `toml:"callback_url"`  // public URL of the callback endpoint. WebSub is disabled when empty.
+	Fallback     int    `toml:"fallback"`      // polling interval in seconds for feeds with a push subscription
+	LeaseSeconds int    `toml:"lease_seconds"` // requested lease time for subscriptions
+	Listen       string `toml:"listen"`        // address for the callback endpoint, e.g. ":8080"
+}
+
+// IsEnabled reports wether WebSub is enabled.
+func (c ConfigWebSub) IsEnabled() bool {
+	return c.CallbackURL != ""
+}
+
+// ConfigTranslator defines a LibreTranslate compatible service for translating items.
+type ConfigTranslator struct {
+	APIKey  string `toml:"api_key"`
+	Timeout int    `toml:"timeout"` // timeout for translation requests in seconds
+	URL     string `toml:"url"`     // URL of the translate endpoint, e.g. "http://localhost:5000/translate"
+}
+
+// IsEnabled reports wether a translator is configured.
+func (c ConfigTranslator) IsEnabled() bool {
+	return c.URL != ""
+}
+
+// ConfigTranslate defines how the items of a feed are translated.
+type ConfigTranslate struct {
+	Source string `toml:"source"` // language code of the feed. Detected automatically when empty.
+	Target string `toml:"target"` // language code of the translation
+}
+
+// ConfigJSONAPI defines how items are mapped from a JSON endpoint.
+// Paths are dot separated keys and array indices, e.g. "assets.0.url".
+// All paths except Items are relative to an item.
+type ConfigJSONAPI struct {
+	Content    string `toml:"content"`     // path to the content of an item
+	DateFormat string `toml:"date_format"` // Go layout for parsing dates. Common formats are tried when empty.
+	GUID       string `toml:"guid"`        // path to the unique ID of an item
+	Image      string `toml:"image"`       // path to the image URL of an item
+	Items      string `toml:"items"`       // path to the array of items. The root is used when empty.
+	Link       string `toml:"link"`        // path to the link of an item
+	Published  string `toml:"published"`   // path to the publishing date of an item. Numbers are treated as Unix time.
+	Title      string `toml:"title"`       // path to the title of an item
+}
+
+// ConfigScrape defines how items are scraped from a web page.
+// All selectors except Item are relative to an item's container.
+type ConfigScrape struct {
+	Content    string `toml:"content"`     // selector for the content of an item
+	Date       string `toml:"date"`        // selector for the publishing date of an item
+	DateFormat string `toml:"date_format"` // Go layout for parsing dates. Common formats are tried when empty.
+	Item       string `toml:"item"`        // selector for the container of each item
+	Link       string `toml:"link"`        // selector for the link of an item
+	Title      string `toml:"title"`       // selector for the title of an item
+}
+
+// ConfigHTTP defines settings for outbound HTTP connections.
+type ConfigHTTP struct {
+	CAFiles    []string `toml:"ca_files"`    // additional CA certificates in PEM format
+	ClientCert string   `toml:"client_cert"` // client certificate in PEM format
+	ClientKey  string   `toml:"client_key"`  // key for the client certificate in PEM format
+	Proxy      string   `toml:"proxy"`       // proxy URL, e.g. http://proxy:8080 or socks5://proxy:1080
+}
+
+// IsEmpty reports wether no settings are defined.
+func (c ConfigHTTP) IsEmpty() bool {
+	return len(c.CAFiles) == 0 && c.ClientCert == "" && c.ClientKey == "" && c.Proxy == ""
+}
+
+// Merge returns a copy of the settings with all settings from o applied.
+// CA files are added and all other defined settings from o replace the current settings.
+func (c ConfigHTTP) Merge(o *ConfigHTTP) ConfigHTTP {
+	if o == nil {
+		return c
+	}
+	x := c
+	x.CAFiles = append(slices.Clone(c.CAFiles), o.CAFiles...)
+	if o.ClientCert != "" {
+		x.ClientCert = o.ClientCert
+		x.ClientKey = o.ClientKey
+	}
+	if o.Proxy != "" {
+		x.Proxy = o.Proxy
+	}
+	return x
+}
+
+func (c ConfigHTTP) validate() error {
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %w", err)
+		}
+		if !slices.Contains([]string{"http", "https", "socks5"}, u.Scheme) {
+			return fmt.Errorf("invalid proxy: unsupported scheme: %s", u.Scheme)
+		}
+	}
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return fmt.Errorf("client certificate requires both a cert and a key")
+	}
+	files := slices.Clone(c.CAFiles)
+	if c.ClientCert != "" {
+		files = append(files, c.ClientCert, c.ClientKey)
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (ca ConfigApp) LoggerLevel() slog.Level {
@@ -52,16 +231,319 @@ func (ca ConfigApp) LoggerLevel() slog.Level {
 	return v
 }
 
+// Feed types
+const (
+	FeedTypeFeed    = "feed"     // RSS, Atom or JSON feed (default)
+	FeedTypeJSONAPI = "json_api" // JSON endpoint mapped with field paths
+	FeedTypeScrape  = "scrape"   // web page scraped with CSS selectors
+)
+
 type ConfigFeed struct {
 	Name     string   `toml:"name"`
-	URL      string   `toml:"url"`
+	URL      string   `toml:"url"`     // http(s) or file URL
+	Command  []string `toml:"command"` // command and arguments, which outputs the feed to stdout. Alternative to an URL.
 	Webhooks []string `toml:"webhooks"`
 	Disabled bool     `toml:"disabled"`
+	Type     string   `toml:"type"`     // type of the source. Defaults to "feed".
+	OGImage  bool     `toml:"og_image"` // fetch the Open Graph image of the linked article for items without image
+
+	JSONAPI *ConfigJSONAPI `toml:"json_api"` // field paths for mapping a JSON endpoint. Required for type "json_api".
+	Scrape  *ConfigScrape  `toml:"scrape"`   // selectors for scraping a web page. Required for type "scrape".
+
+	BasicAuth       *ConfigBasicAuth  `toml:"basic_auth"`
+	BearerTokenFile string            `toml:"bearer_token_file"` // file containing a bearer token
+	Headers         map[string]string `toml:"headers"`           // custom HTTP headers
+	HTTP            *ConfigHTTP       `toml:"http"`              // overrides global settings for outbound HTTP connections
+
+	Retention *ConfigRetention `toml:"retention"` // overrides global retention
+
+	ItemID       string   `toml:"item_id"`        // what identifies an item. Defaults to "guid".
+	ItemIDFields []string `toml:"item_id_fields"` // fields for item ID "hash"
+
+	UpdateInterval int    `toml:"update_interval"` // min seconds between update notifications for an item
+	UpdatePolicy   string `toml:"update_policy"`   // what identifies an updated item. Defaults to "published_date".
+
+	LongContent string `toml:"long_content"` // how long descriptions are handled. Defaults to "truncate".
+	MaxMessages int    `toml:"max_messages"` // max number of messages per item for long content "split"
+
+	Mention *ConfigMention `toml:"mention"` // roles and users mentioned in messages for this feed
+
+	ThreadID  string   `toml:"thread_id"`  // ID of the thread to post into. Overrides the webhook's thread.
+	ForumTags []string `toml:"forum_tags"` // IDs of tags applied to forum posts. Overrides the webhook's tags.
+
+	ConfigAppearance // overrides the webhook's appearance
+
+	Buttons []string `toml:"buttons"` // link buttons shown below a post: "article", "comments"
+
+	Markdown *ConfigMarkdown `toml:"markdown"` // how the HTML of items is converted
+
+	Translate *ConfigTranslate `toml:"translate"` // translates title and description of items
+
+	Transform []ConfigTransformer `toml:"transform"` // transformers applied to items in order
+}
+
+// Transformer types
+const (
+	TransformCategoryPrefix = "category_prefix" // prefix the title with the item's categories
+	TransformRegexReplace   = "regex_replace"   // replace matches of a regular expression in a field
+	TransformRewriteDomain  = "rewrite_domain"  // rewrite the domain of a link, e.g. to a frontend like nitter
+	TransformStripTracking  = "strip_tracking"  // remove tracking parameters from a link
+	TransformTemplate       = "template"        // set a field from a template
+)
+
+// Fields which can be changed by transformers
+var TransformFields = []string{"content", "description", "link", "title"}
+
+// ConfigTransformer defines a transformer, which changes items before they are posted.
+type ConfigTransformer struct {
+	Type     string `toml:"type"`
+	Field    string `toml:"field"`    // field to change. Defaults to "link" for "rewrite_domain" and "strip_tracking" and "title" otherwise.
+	Pattern  string `toml:"pattern"`  // regular expression for "regex_replace"
+	Replace  string `toml:"replace"`  // replacement for "regex_replace". Can contain references like $1.
+	From     string `toml:"from"`     // domain to rewrite for "rewrite_domain"
+	To       string `toml:"to"`       // new domain for "rewrite_domain"
+	Template string `toml:"template"` // Go template for "template", e.g. "{{.Title}} by {{.Author.Name}}"
+}
+
+func (ct ConfigTransformer) validate() error {
+	if ct.Field != "" && !slices.Contains(TransformFields, ct.Field) {
+		return fmt.Errorf("invalid field: %s", ct.Field)
+	}
+	switch ct.Type {
+	case TransformCategoryPrefix:
+	case TransformRegexReplace:
+		if ct.Pattern == "" {
+			return fmt.Errorf("regex_replace: pattern not defined")
+		}
+		if _, err := regexp.Compile(ct.Pattern); err != nil {
+			return fmt.Errorf("regex_replace: %w", err)
+		}
+	case TransformRewriteDomain:
+		if ct.From == "" || ct.To == "" {
+			return fmt.Errorf("rewrite_domain: from and to must be defined")
+		}
+		if ct.Field != "" && ct.Field != "link" {
+			return fmt.Errorf("rewrite_domain: only supports field link")
+		}
+	case TransformStripTracking:
+		if ct.Field != "" && ct.Field != "link" {
+			return fmt.Errorf("strip_tracking: only supports field link")
+		}
+	case TransformTemplate:
+		if ct.Template == "" {
+			return fmt.Errorf("template: template not defined")
+		}
+		if _, err := template.New("").Parse(ct.Template); err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid type: %s", ct.Type)
+	}
+	return nil
+}
+
+// Table modes
+const (
+	TablesKeep  = "keep"  // convert to text tables with aligned columns in a code block
+	TablesStrip = "strip" // remove tables
+)
+
+// ConfigMarkdown defines how the HTML of items is converted to markdown.
+type ConfigMarkdown struct {
+	ImagesAsLinks bool     `toml:"images_as_links"` // show images as links instead of removing them
+	MaxParagraphs int      `toml:"max_paragraphs"`  // max number of paragraphs. 0 = unlimited
+	PlainText     bool     `toml:"plain_text"`      // convert to text without formatting
+	Remove        []string `toml:"remove"`          // CSS selectors of elements to remove, e.g. ads
+	Tables        string   `toml:"tables"`          // "keep" or "strip" tables. Shows the text of cells by default.
+}
+
+func (cm ConfigMarkdown) validate() error {
+	if cm.MaxParagraphs < 0 {
+		return fmt.Errorf("max_paragraphs can not be negative")
+	}
+	if cm.Tables != "" && cm.Tables != TablesKeep && cm.Tables != TablesStrip {
+		return fmt.Errorf("invalid tables: %s", cm.Tables)
+	}
+	for _, sel := range cm.Remove {
+		if strings.TrimSpace(sel) == "" {
+			return fmt.Errorf("remove can not contain empty selectors")
+		}
+		if _, err := cascadia.Compile(sel); err != nil {
+			return fmt.Errorf("remove: invalid selector %q: %w", sel, err)
+		}
+	}
+	return nil
+}
+
+// Buttons
+const (
+	ButtonArticle  = "article"  // opens the item's link
+	ButtonComments = "comments" // opens the item's comments
+)
+
+// Long content modes
+const (
+	LongContentReadMore = "read_more" // truncate and link to the item
+	LongContentSplit    = "split"     // split into several embeds and messages
+	LongContentTruncate = "truncate"  // truncate (default)
+)
+
+// Update policies
+const (
+	UpdatePolicyContentHash   = "content_hash"   // title, description or content changed
+	UpdatePolicyNone          = "none"           // updates are ignored
+	UpdatePolicyPublishedDate = "published_date" // published date changed (default)
+	UpdatePolicyUpdatedDate   = "updated_date"   // updated date changed
+)
+
+// Item IDs
+const (
+	ItemIDGUID           = "guid"            // GUID or a hash of title, description and content when an item has no GUID (default)
+	ItemIDHash           = "hash"            // hash of selected fields
+	ItemIDLink           = "link"            // link
+	ItemIDNormalizedLink = "normalized_link" // link without tracking parameters
+	ItemIDTitle          = "title"           // title
+)
+
+// Fields for item ID "hash"
+var ItemIDHashFields = []string{"content", "description", "guid", "link", "published", "title"}
+
+type ConfigBasicAuth struct {
+	Username string `toml:"username"`
+	Password string `toml:"password"`
 }
 
 type ConfigWebhook struct {
 	Name string `toml:"name"`
 	URL  string `toml:"url"`
+
+	Dedup   *ConfigDedup   `toml:"dedup"`   // skips items already delivered from another feed
+	HTTP    *ConfigHTTP    `toml:"http"`    // overrides global settings for outbound HTTP connections
+	Mention *ConfigMention `toml:"mention"` // roles and users mentioned in messages to this webhook
+
+	Forum     bool     `toml:"forum"`      // creates a forum post for each item
+	ForumTags []string `toml:"forum_tags"` // IDs of tags applied to forum posts
+	ThreadID  string   `toml:"thread_id"`  // ID of the thread to post into
+
+	ConfigAppearance
+}
+
+// Embed colors derived from a feed
+const (
+	ColorHash = "hash" // color derived from the feed's name
+	ColorIcon = "icon" // average color of the feed's icon. Falls back to "hash".
+)
+
+// ConfigAppearance defines how messages look on Discord.
+type ConfigAppearance struct {
+	AvatarFeedIcon bool   `toml:"avatar_feed_icon"` // use the feed's icon as avatar, when no avatar URL is defined
+	AvatarURL      string `toml:"avatar_url"`
+	Color          string `toml:"color"` // color of embeds as hex, e.g. "#1e90ff", or "hash" or "icon"
+	Username       string `toml:"username"`
+}
+
+func (ca ConfigAppearance) validate() error {
+	if n := len([]rune(ca.Username)); n > usernameMaxLength {
+		return fmt.Errorf("username can not be longer then %d characters", usernameMaxLength)
+	}
+	if u := strings.ToLower(ca.Username); strings.Contains(u, "discord") || strings.Contains(u, "clyde") {
+		return fmt.Errorf("username can not contain \"discord\" or \"clyde\"")
+	}
+	if ca.AvatarURL != "" {
+		u, err := url.ParseRequestURI(ca.AvatarURL)
+		if err != nil {
+			return fmt.Errorf("avatar_url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("avatar_url: scheme not supported: %s", u.Scheme)
+		}
+	}
+	switch ca.Color {
+	case "", ColorHash, ColorIcon:
+	default:
+		if _, err := ParseColor(ca.Color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseColor returns the value of a hex color, e.g. "#1e90ff".
+func ParseColor(s string) (int, error) {
+	h, ok := strings.CutPrefix(s, "#")
+	if !ok || len(h) != 6 {
+		return 0, fmt.Errorf("invalid color: %s", s)
+	}
+	v, err := strconv.ParseInt(h, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color: %s", s)
+	}
+	return int(v), nil
+}
+
+// ConfigMention defines which roles and users are mentioned in messages.
+type ConfigMention struct {
+	Keywords []string `toml:"keywords"` // mention only when an item contains one of the keywords. Case insensitive.
+	Roles    []string `toml:"roles"`    // IDs of roles
+	Users    []string `toml:"users"`    // IDs of users
+}
+
+func (cm ConfigMention) validate() error {
+	if len(cm.Roles) == 0 && len(cm.Users) == 0 {
+		return fmt.Errorf("no roles or users defined")
+	}
+	for _, id := range slices.Concat(cm.Roles, cm.Users) {
+		if !isSnowflake(id) {
+			return fmt.Errorf("invalid ID: %s", id)
+		}
+	}
+	for _, k := range cm.Keywords {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("keywords can not be empty")
+		}
+	}
+	return nil
+}
+
+// validateThread validates the thread settings of a webhook or feed.
+func validateThread(threadID string, forumTags []string) error {
+	if threadID != "" && !isSnowflake(threadID) {
+		return fmt.Errorf("invalid thread_id: %s", threadID)
+	}
+	if len(forumTags) > forumTagsMax {
+		return fmt.Errorf("forum_tags can have at most %d tags", forumTagsMax)
+	}
+	for _, id := range forumTags {
+		if !isSnowflake(id) {
+			return fmt.Errorf("invalid forum tag: %s", id)
+		}
+	}
+	return nil
+}
+
+// isSnowflake reports wether s is a valid Discord ID.
+func isSnowflake(s string) bool {
+	if s == "" || len(s) > 20 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Dedup keys
+const (
+	DedupKeyGUID = "guid" // GUID of an item. Falls back to the link when an item has no GUID.
+	DedupKeyLink = "link" // normalized link of an item (default)
+)
+
+// ConfigDedup defines how items from different feeds are deduplicated for a webhook.
+type ConfigDedup struct {
+	Key    string `toml:"key"`    // what identifies an item. Defaults to "link".
+	Window int    `toml:"window"` // time window in seconds for detecting duplicates
 }
 
 func FromFile(path string) (Config, error) {
@@ -76,6 +558,9 @@ func FromFile(path string) (Config, error) {
 }
 
 func parseConfig(config *Config) error {
+	if err := config.App.HTTP.validate(); err != nil {
+		return fmt.Errorf("app: http: %w", err)
+	}
 	webhookNames := make(map[string]bool)
 	webhookURLs := make(map[string]bool)
 	for _, x := range config.Webhooks {
@@ -96,28 +581,164 @@ func parseConfig(config *Config) error {
 			return fmt.Errorf("webhook name %s no unique", x.Name)
 		}
 		webhookURLs[x.URL] = true
+		if x.HTTP != nil {
+			if err := x.HTTP.validate(); err != nil {
+				return fmt.Errorf("webhook %s: http: %w", x.Name, err)
+			}
+		}
+		if x.Mention != nil {
+			if err := x.Mention.validate(); err != nil {
+				return fmt.Errorf("webhook %s: mention: %w", x.Name, err)
+			}
+		}
+		if err := validateThread(x.ThreadID, x.ForumTags); err != nil {
+			return fmt.Errorf("webhook %s: %w", x.Name, err)
+		}
+		if x.Forum && x.ThreadID != "" {
+			return fmt.Errorf("webhook %s: forum and thread_id can not be used together", x.Name)
+		}
+		if err := x.ConfigAppearance.validate(); err != nil {
+			return fmt.Errorf("webhook %s: %w", x.Name, err)
+		}
+		if x.Dedup != nil {
+			if x.Dedup.Key == "" {
+				x.Dedup.Key = DedupKeyLink
+			}
+			if x.Dedup.Key != DedupKeyLink && x.Dedup.Key != DedupKeyGUID {
+				return fmt.Errorf("webhook %s: dedup: invalid key: %s", x.Name, x.Dedup.Key)
+			}
+			if x.Dedup.Window == 0 {
+				x.Dedup.Window = dedupWindowDefault
+			}
+			if x.Dedup.Window < 0 {
+				return fmt.Errorf("webhook %s: dedup: window can not be negative", x.Name)
+			}
+		}
 	}
 	if len(config.Feeds) == 0 {
 		return fmt.Errorf("no feeds defined")
 	}
 	feedNames := make(map[string]bool)
 	webhooksUsed := make(map[string]bool)
-	for _, x := range config.Feeds {
+	for i, x := range config.Feeds {
 		if x.Name == "" {
 			return fmt.Errorf("feed has no name")
 		}
 		if len(x.Webhooks) == 0 {
 			return fmt.Errorf("feed %s has no webhooks", x.Name)
 		}
-		if x.URL == "" {
+		if x.URL == "" && len(x.Command) == 0 {
 			return fmt.Errorf("feed %s has no url", x.Name)
 		}
+		if x.URL != "" && len(x.Command) > 0 {
+			return fmt.Errorf("feed %s: url and command can not be used together", x.Name)
+		}
 		if feedNames[x.Name] {
 			return fmt.Errorf("feed name %s not unique", x.Name)
 		}
 		feedNames[x.Name] = true
-		if _, err := url.ParseRequestURI(x.URL); err != nil {
-			return fmt.Errorf("feed %s has invalid url: %w", x.Name, err)
+		if x.URL != "" {
+			u, err := url.ParseRequestURI(x.URL)
+			if err != nil {
+				return fmt.Errorf("feed %s has invalid url: %w", x.Name, err)
+			}
+			if !slices.Contains([]string{"http", "https", "file"}, u.Scheme) {
+				return fmt.Errorf("feed %s has invalid url: unsupported scheme: %s", x.Name, u.Scheme)
+			}
+			if u.Scheme == "file" && u.Host != "" && u.Host != "localhost" {
+				return fmt.Errorf("feed %s has invalid url: file path must be absolute", x.Name)
+			}
+		}
+		switch x.Type {
+		case "", FeedTypeFeed:
+		case FeedTypeJSONAPI:
+			if x.JSONAPI == nil || x.JSONAPI.Title == "" {
+				return fmt.Errorf("feed %s: json_api requires a path for title", x.Name)
+			}
+		case FeedTypeScrape:
+			if x.Scrape == nil || x.Scrape.Item == "" || x.Scrape.Title == "" {
+				return fmt.Errorf("feed %s: scrape requires selectors for item and title", x.Name)
+			}
+		default:
+			return fmt.Errorf("feed %s has invalid type: %s", x.Name, x.Type)
+		}
+		switch x.ItemID {
+		case "", ItemIDGUID, ItemIDLink, ItemIDNormalizedLink, ItemIDTitle:
+			if len(x.ItemIDFields) > 0 {
+				return fmt.Errorf("feed %s: item_id_fields requires item_id \"hash\"", x.Name)
+			}
+		case ItemIDHash:
+			if len(x.ItemIDFields) == 0 {
+				return fmt.Errorf("feed %s: item_id \"hash\" requires item_id_fields", x.Name)
+			}
+			for _, f := range x.ItemIDFields {
+				if !slices.Contains(ItemIDHashFields, f) {
+					return fmt.Errorf("feed %s: invalid item_id_fields: %s", x.Name, f)
+				}
+			}
+		default:
+			return fmt.Errorf("feed %s has invalid item_id: %s", x.Name, x.ItemID)
+		}
+		switch x.UpdatePolicy {
+		case "", UpdatePolicyContentHash, UpdatePolicyNone, UpdatePolicyPublishedDate, UpdatePolicyUpdatedDate:
+		default:
+			return fmt.Errorf("feed %s has invalid update_policy: %s", x.Name, x.UpdatePolicy)
+		}
+		if x.Mention != nil {
+			if err := x.Mention.validate(); err != nil {
+				return fmt.Errorf("feed %s: mention: %w", x.Name, err)
+			}
+		}
+		if err := validateThread(x.ThreadID, x.ForumTags); err != nil {
+			return fmt.Errorf("feed %s: %w", x.Name, err)
+		}
+		if err := x.ConfigAppearance.validate(); err != nil {
+			return fmt.Errorf("feed %s: %w", x.Name, err)
+		}
+		if x.Markdown != nil {
+			if err := x.Markdown.validate(); err != nil {
+				return fmt.Errorf("feed %s: markdown: %w", x.Name, err)
+			}
+		}
+		for j, t := range x.Transform {
+			if err := t.validate(); err != nil {
+				return fmt.Errorf("feed %s: transform #%d: %w", x.Name, j+1, err)
+			}
+		}
+		for _, b := range x.Buttons {
+			if b != ButtonArticle && b != ButtonComments {
+				return fmt.Errorf("feed %s has invalid button: %s", x.Name, b)
+			}
+		}
+		switch x.LongContent {
+		case "", LongContentReadMore, LongContentSplit, LongContentTruncate:
+		default:
+			return fmt.Errorf("feed %s has invalid long_content: %s", x.Name, x.LongContent)
+		}
+		if x.MaxMessages < 0 || x.MaxMessages > 10 {
+			return fmt.Errorf("feed %s: max_messages must be between 0 (default) and 10", x.Name)
+		}
+		if x.MaxMessages == 0 {
+			config.Feeds[i].MaxMessages = maxMessagesDefault
+		}
+		if x.UpdateInterval < 0 {
+			return fmt.Errorf("feed %s: update_interval can not be negative", x.Name)
+		}
+		if x.BasicAuth != nil && x.BasicAuth.Username == "" {
+			return fmt.Errorf("feed %s: basic auth has no username", x.Name)
+		}
+		if x.BasicAuth != nil && x.BearerTokenFile != "" {
+			return fmt.Errorf("feed %s: basic auth and bearer token can not be used together", x.Name)
+		}
+		if x.BearerTokenFile != "" {
+			if _, err := os.Stat(x.BearerTokenFile); err != nil {
+				return fmt.Errorf("feed %s: bearer token file: %w", x.Name, err)
+			}
+		}
+		if x.HTTP != nil {
+			if err := x.HTTP.validate(); err != nil {
+				return fmt.Errorf("feed %s: http: %w", x.Name, err)
+			}
 		}
 		feedWebhooks := make(map[string]bool)
 		for _, wh := range x.Webhooks {
@@ -147,5 +768,73 @@ func parseConfig(config *Config) error {
 	if config.App.Ticker <= 0 {
 		config.App.Ticker = tickerDefault
 	}
+	if config.App.BackoffMax <= 0 {
+		config.App.BackoffMax = backoffDefault
+	}
+	if config.App.MaxFetches <= 0 {
+		config.App.MaxFetches = maxFetchesDefault
+	}
+	if config.App.OGImageTimeout <= 0 {
+		config.App.OGImageTimeout = ogImageTimeoutDefault
+	}
+	if config.App.MaxHostFetches <= 0 {
+		config.App.MaxHostFetches = maxHostFetchesDefault
+	}
+	if t := config.App.Translator; t.IsEnabled() {
+		if u, err := url.ParseRequestURI(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("app: translator: invalid url: %s", t.URL)
+		}
+	}
+	if config.App.Translator.Timeout <= 0 {
+		config.App.Translator.Timeout = translatorTimeoutDefault
+	}
+	for _, x := range config.Feeds {
+		if x.Translate == nil {
+			continue
+		}
+		if !config.App.Translator.IsEnabled() {
+			return fmt.Errorf("feed %s: translate: no translator configured", x.Name)
+		}
+		if x.Translate.Target == "" {
+			return fmt.Errorf("feed %s: translate: target not defined", x.Name)
+		}
+		if x.Translate.Source == "" {
+			x.Translate.Source = "auto"
+		}
+	}
+	if config.App.WebSub.IsEnabled() {
+		if _, err := url.ParseRequestURI(config.App.WebSub.CallbackURL); err != nil {
+			return fmt.Errorf("app: websub: invalid callback url: %w", err)
+		}
+		if config.App.WebSub.Listen == "" {
+			return fmt.Errorf("app: websub: listen address not defined")
+		}
+	}
+	if config.App.WebSub.Fallback <= 0 {
+		config.App.WebSub.Fallback = websubFallbackDefault
+	}
+	if config.App.WebSub.LeaseSeconds <= 0 {
+		config.App.WebSub.LeaseSeconds = websubLeaseDefault
+	}
+	if config.App.FetchJitter < 0 {
+		return fmt.Errorf("fetch_jitter can not be negative")
+	}
+	if config.App.FetchJitter >= config.App.Ticker {
+		return fmt.Errorf("fetch_jitter must be less then ticker")
+	}
+	if config.App.Retention.MaxAge == 0 && config.App.Retention.MaxItems == 0 {
+		config.App.Retention.MaxItems = maxItemsDefault
+	}
+	if err := config.App.Retention.validate(config.App.Oldest); err != nil {
+		return fmt.Errorf("app: retention: %w", err)
+	}
+	for _, x := range config.Feeds {
+		if x.Retention == nil {
+			continue
+		}
+		if err := config.FeedRetention(x).validate(config.App.Oldest); err != nil {
+			return fmt.Errorf("feed %s: retention: %w", x.Name, err)
+		}
+	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"log/slog"
 	"testing"
 
@@ -82,6 +83,394 @@ func TestParseConfig(t *testing.T) {
 		}
 		assert.Error(t, parseConfig(&cf))
 	})
+	t.Run("should return error when a feed url has unsupported scheme", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "ftp://www.example.com/feed.xml", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should accept file url and command as feed source", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{
+				{Name: "feed1", URL: "file:///var/feeds/feed.xml", Webhooks: []string{"hook1"}},
+				{Name: "feed2", Command: []string{"/usr/local/bin/feed.sh", "--all"}, Webhooks: []string{"hook1"}},
+			},
+		}
+		assert.NoError(t, parseConfig(&cf))
+	})
+	t.Run("should return error when feed has both url and command", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:     "feed1",
+				URL:      "https://www.example.com/url2",
+				Command:  []string{"/usr/local/bin/feed.sh"},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when feed type is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Type: "invalid", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when scrape feed has no selectors", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Type: FeedTypeScrape, Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should accept scrape feed with selectors", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:     "feed1",
+				URL:      "https://www.example.com/url2",
+				Type:     FeedTypeScrape,
+				Scrape:   &ConfigScrape{Item: "div.release", Title: "h2"},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.NoError(t, parseConfig(&cf))
+	})
+	t.Run("should return error when json api feed has no title path", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:     "feed1",
+				URL:      "https://www.example.com/url2",
+				Type:     FeedTypeJSONAPI,
+				JSONAPI:  &ConfigJSONAPI{Link: "url"},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should set dedup defaults for webhook", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1", Dedup: &ConfigDedup{}}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		if assert.NoError(t, parseConfig(&cf)) {
+			assert.Equal(t, DedupKeyLink, cf.Webhooks[0].Dedup.Key)
+			assert.Equal(t, dedupWindowDefault, cf.Webhooks[0].Dedup.Window)
+		}
+	})
+	t.Run("should return error when dedup key is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1", Dedup: &ConfigDedup{Key: "invalid"}}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when retention max age is less then oldest", func(t *testing.T) {
+		cf := Config{
+			App:      ConfigApp{Oldest: 7200, Retention: ConfigRetention{MaxAge: 3600}},
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when feed retention max age is less then oldest", func(t *testing.T) {
+		cf := Config{
+			App:      ConfigApp{Oldest: 7200},
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:      "feed1",
+				URL:       "https://www.example.com/url2",
+				Webhooks:  []string{"hook1"},
+				Retention: &ConfigRetention{MaxAge: 3600},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should accept retention max age when oldest is disabled", func(t *testing.T) {
+		cf := Config{
+			App:      ConfigApp{Oldest: -1, Retention: ConfigRetention{MaxAge: 86400}},
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		assert.NoError(t, parseConfig(&cf))
+	})
+	t.Run("should return error when item id is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", ItemID: "invalid", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when item id hash has no fields", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", ItemID: ItemIDHash, Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when item id hash has invalid field", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:         "feed1",
+				URL:          "https://www.example.com/url2",
+				ItemID:       ItemIDHash,
+				ItemIDFields: []string{"title", "invalid"},
+				Webhooks:     []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when update policy is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", UpdatePolicy: "invalid", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when feed mention has invalid ID", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:     "feed1",
+				URL:      "https://www.example.com/url2",
+				Mention:  &ConfigMention{Roles: []string{"@SRE"}},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when webhook mention has no roles or users", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1", Mention: &ConfigMention{Keywords: []string{"CVE"}}}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when webhook has forum and thread", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1", Forum: true, ThreadID: "1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when feed thread ID is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", ThreadID: "abc", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when webhook has too many forum tags", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{
+				Name:      "hook1",
+				URL:       "https://www.example.com/url1",
+				Forum:     true,
+				ForumTags: []string{"1", "2", "3", "4", "5", "6"},
+			}},
+			Feeds: []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when webhook color is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1", ConfigAppearance: ConfigAppearance{Color: "blue"}}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when feed username contains discord", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:             "feed1",
+				URL:              "https://www.example.com/url2",
+				ConfigAppearance: ConfigAppearance{Username: "My Discord Bot"},
+				Webhooks:         []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when feed avatar URL is not http", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:             "feed1",
+				URL:              "https://www.example.com/url2",
+				ConfigAppearance: ConfigAppearance{AvatarURL: "file:///avatar.png"},
+				Webhooks:         []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when markdown tables is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:     "feed1",
+				URL:      "https://www.example.com/url2",
+				Markdown: &ConfigMarkdown{Tables: "invalid"},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when markdown max paragraphs is negative", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:     "feed1",
+				URL:      "https://www.example.com/url2",
+				Markdown: &ConfigMarkdown{MaxParagraphs: -1},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when markdown remove selector is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:     "feed1",
+				URL:      "https://www.example.com/url2",
+				Markdown: &ConfigMarkdown{Remove: []string{".ad", "p[class="}},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should accept valid markdown remove selectors", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:     "feed1",
+				URL:      "https://www.example.com/url2",
+				Markdown: &ConfigMarkdown{Remove: []string{".ad", "p.read-more, div > aside"}},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.NoError(t, parseConfig(&cf))
+	})
+	t.Run("should accept valid transformers", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name: "feed1",
+				URL:  "https://www.example.com/url2",
+				Transform: []ConfigTransformer{
+					{Type: TransformRegexReplace, Field: "title", Pattern: `\s*\(sponsored\)`},
+					{Type: TransformStripTracking},
+					{Type: TransformRewriteDomain, From: "twitter.com", To: "nitter.net"},
+					{Type: TransformCategoryPrefix},
+					{Type: TransformTemplate, Field: "title", Template: "{{.Title}} by {{.Author.Name}}"},
+				},
+				Webhooks: []string{"hook1"},
+			}},
+		}
+		assert.NoError(t, parseConfig(&cf))
+	})
+	t.Run("should return error when transformer is invalid", func(t *testing.T) {
+		cases := []ConfigTransformer{
+			{Type: "invalid"},
+			{Type: TransformRegexReplace},
+			{Type: TransformRegexReplace, Pattern: "("},
+			{Type: TransformRegexReplace, Pattern: "x", Field: "invalid"},
+			{Type: TransformRewriteDomain, From: "twitter.com"},
+			{Type: TransformStripTracking, Field: "content"},
+			{Type: TransformTemplate},
+			{Type: TransformTemplate, Template: "{{.Title"},
+		}
+		for i, tc := range cases {
+			t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+				cf := Config{
+					Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+					Feeds: []ConfigFeed{{
+						Name:      "feed1",
+						URL:       "https://www.example.com/url2",
+						Transform: []ConfigTransformer{tc},
+						Webhooks:  []string{"hook1"},
+					}},
+				}
+				assert.Error(t, parseConfig(&cf))
+			})
+		}
+	})
+	t.Run("should return error when feed is translated without translator", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:      "feed1",
+				URL:       "https://www.example.com/url2",
+				Translate: &ConfigTranslate{Target: "en"},
+				Webhooks:  []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when translation has no target", func(t *testing.T) {
+		cf := Config{
+			App:      ConfigApp{Translator: ConfigTranslator{URL: "http://localhost:5000/translate"}},
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:      "feed1",
+				URL:       "https://www.example.com/url2",
+				Translate: &ConfigTranslate{Source: "de"},
+				Webhooks:  []string{"hook1"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should set translation defaults", func(t *testing.T) {
+		cf := Config{
+			App:      ConfigApp{Translator: ConfigTranslator{URL: "http://localhost:5000/translate"}},
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:      "feed1",
+				URL:       "https://www.example.com/url2",
+				Translate: &ConfigTranslate{Target: "en"},
+				Webhooks:  []string{"hook1"},
+			}},
+		}
+		if assert.NoError(t, parseConfig(&cf)) {
+			assert.Equal(t, "auto", cf.Feeds[0].Translate.Source)
+			assert.Equal(t, translatorTimeoutDefault, cf.App.Translator.Timeout)
+		}
+	})
+	t.Run("should return error when button is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Buttons: []string{"invalid"}, Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when long content is invalid", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", LongContent: "invalid", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when max messages is too large", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", MaxMessages: 11, Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should set feed max messages default when missing", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", LongContent: LongContentSplit, Webhooks: []string{"hook1"}}},
+		}
+		if assert.NoError(t, parseConfig(&cf)) {
+			assert.Equal(t, maxMessagesDefault, cf.Feeds[0].MaxMessages)
+		}
+	})
 	t.Run("should set app defaults when missing", func(t *testing.T) {
 		cf := Config{
 			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
@@ -92,7 +481,56 @@ func TestParseConfig(t *testing.T) {
 			assert.Equal(t, cf.App.Timeout, timeoutDefault)
 			assert.Equal(t, cf.App.Oldest, oldestDefault)
 			assert.Equal(t, cf.App.Ticker, tickerDefault)
+			assert.Equal(t, cf.App.BackoffMax, backoffDefault)
+			assert.Equal(t, cf.App.MaxFetches, maxFetchesDefault)
+			assert.Equal(t, cf.App.MaxHostFetches, maxHostFetchesDefault)
+			assert.Equal(t, cf.App.OGImageTimeout, ogImageTimeoutDefault)
+			assert.Equal(t, cf.App.WebSub.Fallback, websubFallbackDefault)
+			assert.Equal(t, cf.App.WebSub.LeaseSeconds, websubLeaseDefault)
+			assert.Equal(t, cf.App.Retention.MaxItems, maxItemsDefault)
+			assert.Equal(t, cf.App.Retention.MaxAge, 0)
+			assert.False(t, cf.App.WebSub.IsEnabled())
+		}
+	})
+	t.Run("should return error when fetch jitter is not less then ticker", func(t *testing.T) {
+		cf := Config{
+			App:      ConfigApp{Ticker: 30, FetchJitter: 30},
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when basic auth has no username", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:      "feed1",
+				URL:       "https://www.example.com/url2",
+				Webhooks:  []string{"hook1"},
+				BasicAuth: &ConfigBasicAuth{Password: "secret"},
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when bearer token file does not exist", func(t *testing.T) {
+		cf := Config{
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds: []ConfigFeed{{
+				Name:            "feed1",
+				URL:             "https://www.example.com/url2",
+				Webhooks:        []string{"hook1"},
+				BearerTokenFile: "/does/not/exist",
+			}},
+		}
+		assert.Error(t, parseConfig(&cf))
+	})
+	t.Run("should return error when websub is enabled without listen address", func(t *testing.T) {
+		cf := Config{
+			App:      ConfigApp{WebSub: ConfigWebSub{CallbackURL: "https://feedhook.example.com/websub"}},
+			Webhooks: []ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/url1"}},
+			Feeds:    []ConfigFeed{{Name: "feed1", URL: "https://www.example.com/url2", Webhooks: []string{"hook1"}}},
 		}
+		assert.Error(t, parseConfig(&cf))
 	})
 	t.Run("should return error when webhook names not unique", func(t *testing.T) {
 		cf := Config{
@@ -165,3 +603,77 @@ func TestEnabledFeeds(t *testing.T) {
 		assert.Len(t, f, 1)
 	})
 }
+
+func TestConfigHTTP(t *testing.T) {
+	t.Run("can merge settings", func(t *testing.T) {
+		c1 := ConfigHTTP{CAFiles: []string{"a.pem"}, Proxy: "http://proxy1:8080"}
+		c2 := &ConfigHTTP{CAFiles: []string{"b.pem"}, Proxy: "socks5://proxy2:1080"}
+		got := c1.Merge(c2)
+		assert.Equal(t, []string{"a.pem", "b.pem"}, got.CAFiles)
+		assert.Equal(t, "socks5://proxy2:1080", got.Proxy)
+		assert.Equal(t, []string{"a.pem"}, c1.CAFiles)
+	})
+	t.Run("should keep settings when merging with nil", func(t *testing.T) {
+		c1 := ConfigHTTP{Proxy: "http://proxy1:8080"}
+		got := c1.Merge(nil)
+		assert.Equal(t, c1, got)
+	})
+	t.Run("should return error when proxy scheme is not supported", func(t *testing.T) {
+		c := ConfigHTTP{Proxy: "ftp://proxy:21"}
+		assert.Error(t, c.validate())
+	})
+	t.Run("should return error when client cert has no key", func(t *testing.T) {
+		c := ConfigHTTP{ClientCert: "cert.pem"}
+		assert.Error(t, c.validate())
+	})
+	t.Run("should return error when CA file does not exist", func(t *testing.T) {
+		c := ConfigHTTP{CAFiles: []string{"/does/not/exist.pem"}}
+		assert.Error(t, c.validate())
+	})
+	t.Run("should accept valid proxy", func(t *testing.T) {
+		c := ConfigHTTP{Proxy: "socks5://proxy:1080"}
+		assert.NoError(t, c.validate())
+	})
+}
+
+func TestParseColor(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"#1e90ff", 0x1e90ff, false},
+		{"#FFFFFF", 0xffffff, false},
+		{"1e90ff", 0, true},
+		{"#1e90f", 0, true},
+		{"#gggggg", 0, true},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			got, err := ParseColor(tc.in)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFeedRetention(t *testing.T) {
+	cfg := Config{App: ConfigApp{Retention: ConfigRetention{MaxAge: 86400, MaxItems: 1000}}}
+	t.Run("should return global retention when feed has none", func(t *testing.T) {
+		got := cfg.FeedRetention(ConfigFeed{Name: "feed1"})
+		assert.Equal(t, ConfigRetention{MaxAge: 86400, MaxItems: 1000}, got)
+	})
+	t.Run("should override global retention with feed retention", func(t *testing.T) {
+		got := cfg.FeedRetention(ConfigFeed{Name: "feed1", Retention: &ConfigRetention{MaxItems: 5000}})
+		assert.Equal(t, ConfigRetention{MaxAge: 86400, MaxItems: 5000}, got)
+	})
+	t.Run("should inherit global limits for zero values of feed", func(t *testing.T) {
+		got := cfg.FeedRetention(ConfigFeed{Name: "feed1", Retention: &ConfigRetention{MaxAge: 0, MaxItems: 0}})
+		assert.Equal(t, ConfigRetention{MaxAge: 86400, MaxItems: 1000}, got)
+	})
+}
@@ -16,15 +16,22 @@ oldest = 3
 ticker = 4
 loglevel = "DEBUG"
 db_path = "/path1/alpha"
+user_agent = "agent"
 
 [[webhooks]]
 name = "hook-1"
 url = "https://www.example.com/webhook"
+username = "Hook"
+color = "icon"
 
 [[feeds]]
 name = "Feed 1"
 url = "https://www.example.com/feed.rss"
 webhooks = ["hook-1"]
+headers = { X-Custom = "alpha" }
+basic_auth = { username = "user", password = "secret" }
+avatar_feed_icon = true
+color = "#1e90ff"
 `
 
 func TestConfig(t *testing.T) {
@@ -39,10 +46,17 @@ func TestConfig(t *testing.T) {
 		assert.Equal(t, cf.App.Ticker, 4)
 		assert.Equal(t, cf.App.LogLevel, "DEBUG")
 		assert.Equal(t, cf.App.DBPath, "/path1/alpha")
+		assert.Equal(t, cf.App.UserAgent, "agent")
 		assert.Equal(t, cf.Webhooks[0].Name, "hook-1")
 		assert.Equal(t, cf.Webhooks[0].URL, "https://www.example.com/webhook")
+		assert.Equal(t, cf.Webhooks[0].Username, "Hook")
+		assert.Equal(t, cf.Webhooks[0].Color, "icon")
 		assert.Equal(t, cf.Feeds[0].Name, "Feed 1")
 		assert.Equal(t, cf.Feeds[0].URL, "https://www.example.com/feed.rss")
 		assert.Equal(t, cf.Feeds[0].Webhooks, []string{"hook-1"})
+		assert.Equal(t, cf.Feeds[0].Headers, map[string]string{"X-Custom": "alpha"})
+		assert.Equal(t, cf.Feeds[0].BasicAuth, &config.ConfigBasicAuth{Username: "user", Password: "secret"})
+		assert.True(t, cf.Feeds[0].AvatarFeedIcon)
+		assert.Equal(t, cf.Feeds[0].Color, "#1e90ff")
 	}
 }
@@ -0,0 +1,9 @@
+package app
+
+import "time"
+
+// DeliveredItem represents an item delivered to a webhook with deduplication.
+type DeliveredItem struct {
+	FeedName    string
+	DeliveredAt time.Time
+}
@@ -0,0 +1,20 @@
+package dispatcher
+
+import (
+	"github.com/mmcdole/gofeed"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/urls"
+)
+
+// dedupKey returns the key for detecting duplicate items across feeds.
+// Returns an empty string when an item can not be identified.
+func dedupKey(cd config.ConfigDedup, item *gofeed.Item) string {
+	if cd.Key == config.DedupKeyGUID && item.GUID != "" {
+		return "guid:" + item.GUID
+	}
+	if item.Link != "" {
+		return "link:" + urls.Normalize(item.Link)
+	}
+	return ""
+}
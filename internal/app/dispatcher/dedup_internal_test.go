@@ -0,0 +1,31 @@
+package dispatcher
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestDedupKey(t *testing.T) {
+	link := config.ConfigDedup{Key: config.DedupKeyLink}
+	guid := config.ConfigDedup{Key: config.DedupKeyGUID}
+	cases := []struct {
+		cd   config.ConfigDedup
+		item *gofeed.Item
+		want string
+	}{
+		{link, &gofeed.Item{GUID: "alpha", Link: "https://www.example.com/a?utm_source=x"}, "link:https://www.example.com/a"},
+		{guid, &gofeed.Item{GUID: "alpha", Link: "https://www.example.com/a"}, "guid:alpha"},
+		{guid, &gofeed.Item{Link: "https://www.example.com/a"}, "link:https://www.example.com/a"},
+		{link, &gofeed.Item{GUID: "alpha"}, ""},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, dedupKey(tc.cd, tc.item))
+		})
+	}
+}
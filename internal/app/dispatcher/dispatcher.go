@@ -5,19 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"slices"
-	"sort"
 	"sync"
 	"time"
 
 	"github.com/ErikKalkoken/go-dhook"
 	"github.com/mmcdole/gofeed"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/ErikKalkoken/feedhook/internal/app"
 	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/feedparser"
+	"github.com/ErikKalkoken/feedhook/internal/app/httpclient"
+	"github.com/ErikKalkoken/feedhook/internal/app/iconcolor"
 	"github.com/ErikKalkoken/feedhook/internal/app/messenger"
+	"github.com/ErikKalkoken/feedhook/internal/app/ogimage"
 	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+	"github.com/ErikKalkoken/feedhook/internal/app/transform"
+	"github.com/ErikKalkoken/feedhook/internal/app/translator"
+	"github.com/ErikKalkoken/feedhook/internal/app/websub"
 	"github.com/ErikKalkoken/feedhook/internal/pqueue"
 	"github.com/ErikKalkoken/feedhook/internal/syncedmap"
 )
@@ -38,8 +46,27 @@ type Dispatcher struct {
 	clock      Clock
 	stopped    chan struct{} // shutdown is complete
 	fp         *gofeed.Parser
+	httpClient *http.Client
+	iconColors *iconcolor.Fetcher
+	fetchSlots *semaphore.Weighted                               // limits concurrent fetches
+	hostSlots  *syncedmap.SyncedMap[string, *semaphore.Weighted] // limits concurrent fetches per host
 	messengers *syncedmap.SyncedMap[string, *messenger.Messenger]
+	ogImages   *ogimage.Fetcher
 	st         *storage.Storage
+	translator *translator.Translator // nil when no translator is configured
+
+	translatorMu    sync.Mutex
+	translatorRetry time.Time // translation is skipped until this time after a failure
+
+	dedupMu        sync.Mutex                    // makes checking and recording delivered items atomic
+	dedups         map[string]config.ConfigDedup // webhooks with deduplication
+	feedClients    map[string]*http.Client       // feeds with custom HTTP settings
+	transformers   map[string]transform.Chain    // feeds with transformers
+	webhookClients map[string]*dhook.Client      // webhooks with custom HTTP settings
+
+	feedLocks  *syncedmap.SyncedMap[string, *sync.Mutex] // prevents concurrent processing of a feed
+	lastPolled *syncedmap.SyncedMap[string, time.Time]
+	subscriber *websub.Subscriber // nil when WebSub is disabled or in dry-run mode
 
 	mu        sync.Mutex
 	isRunning bool
@@ -47,23 +74,97 @@ type Dispatcher struct {
 }
 
 // New creates a new App instance and returns it.
-func New(st *storage.Storage, cfg config.Config, clock Clock) *Dispatcher {
-	httpClient := &http.Client{
-		Timeout: time.Duration(cfg.App.Timeout) * time.Second,
+func New(st *storage.Storage, cfg config.Config, clock Clock) (*Dispatcher, error) {
+	timeout := time.Duration(cfg.App.Timeout) * time.Second
+	httpClient, err := httpclient.New(cfg.App.HTTP, timeout)
+	if err != nil {
+		return nil, err
 	}
-	fp := gofeed.NewParser()
+	fp := feedparser.New()
 	fp.Client = httpClient
 	client := dhook.NewClient(dhook.WithHTTPClient(httpClient))
+	feedClients := make(map[string]*http.Client)
+	transformers := make(map[string]transform.Chain)
+	for _, cf := range cfg.Feeds {
+		if len(cf.Transform) > 0 {
+			c, err := transform.New(cf.Transform)
+			if err != nil {
+				return nil, fmt.Errorf("feed %s: %w", cf.Name, err)
+			}
+			transformers[cf.Name] = c
+		}
+		if cf.HTTP == nil {
+			continue
+		}
+		c, err := httpclient.New(cfg.App.HTTP.Merge(cf.HTTP), timeout)
+		if err != nil {
+			return nil, fmt.Errorf("feed %s: %w", cf.Name, err)
+		}
+		feedClients[cf.Name] = c
+	}
+	dedups := make(map[string]config.ConfigDedup)
+	webhookClients := make(map[string]*dhook.Client)
+	for _, cw := range cfg.Webhooks {
+		if cw.Dedup != nil {
+			dedups[cw.Name] = *cw.Dedup
+		}
+		if cw.HTTP == nil {
+			continue
+		}
+		c, err := httpclient.New(cfg.App.HTTP.Merge(cw.HTTP), timeout)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: %w", cw.Name, err)
+		}
+		webhookClients[cw.Name] = dhook.NewClient(dhook.WithHTTPClient(c))
+	}
 	d := &Dispatcher{
-		client:     client,
-		cfg:        cfg,
-		clock:      clock,
-		stopped:    make(chan struct{}),
-		fp:         fp,
-		messengers: syncedmap.New[string, *messenger.Messenger](),
-		st:         st,
+		client:         client,
+		cfg:            cfg,
+		clock:          clock,
+		dedups:         dedups,
+		stopped:        make(chan struct{}),
+		fp:             fp,
+		httpClient:     httpClient,
+		feedClients:    feedClients,
+		transformers:   transformers,
+		webhookClients: webhookClients,
+		fetchSlots:     semaphore.NewWeighted(int64(max(cfg.App.MaxFetches, 1))),
+		hostSlots:      syncedmap.New[string, *semaphore.Weighted](),
+		messengers:     syncedmap.New[string, *messenger.Messenger](),
+		st:             st,
+		feedLocks:      syncedmap.New[string, *sync.Mutex](),
+		lastPolled:     syncedmap.New[string, time.Time](),
+	}
+	userAgent := cfg.App.UserAgent
+	if userAgent == "" {
+		userAgent = fp.UserAgent
 	}
-	return d
+	d.iconColors = iconcolor.New(httpClient, clock, time.Duration(cfg.App.Timeout)*time.Second, userAgent)
+	d.ogImages = ogimage.New(httpClient, clock, time.Duration(cfg.App.OGImageTimeout)*time.Second, userAgent)
+	if t := cfg.App.Translator; t.IsEnabled() {
+		d.translator = translator.New(httpClient, t.URL, t.APIKey, time.Duration(t.Timeout)*time.Second)
+	}
+	if ws := cfg.App.WebSub; ws.IsEnabled() && !cfg.App.DryRun {
+		// no subscriptions in dry-run mode, since they would affect hubs and the callback of the live service
+		d.subscriber = websub.NewSubscriber(httpClient, st, clock, ws.CallbackURL, ws.LeaseSeconds, d.processPushedFeed)
+	}
+	return d, nil
+}
+
+// feedHTTPClient returns the HTTP client for fetching a feed.
+func (d *Dispatcher) feedHTTPClient(cf config.ConfigFeed) *http.Client {
+	if c, ok := d.feedClients[cf.Name]; ok {
+		return c
+	}
+	return d.httpClient
+}
+
+// WebhookClient returns the client for sending messages to a webhook.
+func (d *Dispatcher) WebhookClient(webhookName string) *dhook.Client {
+	if c, ok := d.webhookClients[webhookName]; ok {
+		return c
+	}
+	return d.client
 }
 
 // Stop stops the dispatcher, which includes the gracefully shutdown of all messengers.
@@ -119,7 +220,7 @@ func (d *Dispatcher) Start() error {
 		if err != nil {
 			return err
 		}
-		ms := messenger.NewMessenger(d.client, q, h.Name, h.URL, d.st, d.cfg)
+		ms := messenger.NewMessenger(d.WebhookClient(h.Name), q, h.Name, h.URL, d.st, d.cfg)
 		d.messengers.Store(h.Name, ms)
 		ms.Start()
 	}
@@ -127,6 +228,19 @@ func (d *Dispatcher) Start() error {
 	var wg sync.WaitGroup
 	ticker := time.NewTicker(time.Duration(d.cfg.App.Ticker) * time.Second)
 	feeds := d.cfg.EnabledFeeds()
+	if d.subscriber != nil {
+		names := make([]string, 0, len(feeds))
+		for _, cf := range feeds {
+			names = append(names, cf.Name)
+		}
+		shutdown := d.shutdown
+		go func() {
+			if err := d.subscriber.RemoveObsolete(names); err != nil {
+				slog.Error("Failed to remove obsolete WebSub subscriptions", "error", err)
+			}
+			d.subscriber.Run(shutdown)
+		}()
+	}
 	slog.Info("Started", "feeds", len(feeds), "webhooks", len(d.cfg.Webhooks))
 	go func() {
 	main:
@@ -136,27 +250,43 @@ func (d *Dispatcher) Start() error {
 					slog.Warn("Skipping feed without webhooks", "name", cf.Name)
 					continue
 				}
+				if paused, err := d.st.IsFeedPaused(cf.Name); err != nil {
+					slog.Error("Failed to read paused state for feed", "feed", cf.Name, "error", err)
+				} else if paused {
+					slog.Debug("Skipping paused feed", "name", cf.Name)
+					continue
+				}
 				wg.Add(1)
 				go func() {
 					defer wg.Done()
-					usedHooks := make([]*messenger.Messenger, 0)
-					for _, name := range cf.Webhooks {
-						wh, ok := d.messengers.Load(name)
-						if !ok {
-							panic("expected webhook not found: " + name)
-						}
-						usedHooks = append(usedHooks, wh)
+					if d.waitJitter() {
+						slog.Debug("user aborted")
+						return
 					}
-					if err := d.processFeed(cf, usedHooks); err == errUserAborted {
+					fs, err := d.st.GetFeedStats(cf.Name)
+					if err != nil {
+						slog.Error("Failed to read feed stats", "feed", cf.Name, "error", err)
+					} else if fs.NextPoll.After(d.clock.Now()) {
+						slog.Debug("Skipping failing feed until next poll", "feed", cf.Name, "nextPoll", fs.NextPoll)
+						return
+					}
+					if d.isPushedFeed(cf) {
+						slog.Debug("Skipping pushed feed until fallback poll", "feed", cf.Name)
+						return
+					}
+					err = d.processFeed(cf, d.feedMessengers(cf))
+					if err == errUserAborted {
 						slog.Debug("user aborted")
 						return
-					} else if err != nil {
-						slog.Error("Failed to process feed", "feed", cf.Name, "error", err)
+					}
+					if err != nil || fs == nil || fs.ConsecutiveErrors > 0 {
+						d.updateFeedHealth(cf, err)
 					}
 				}()
 			}
 			wg.Wait()
 			slog.Info("Finished processing feeds", "feeds", len(feeds))
+			d.cullDelivered()
 			select {
 			case <-d.shutdown:
 				break main
@@ -170,36 +300,79 @@ func (d *Dispatcher) Start() error {
 	return nil
 }
 
+// feedMessengers returns the messengers for all webhooks of a feed.
+func (d *Dispatcher) feedMessengers(cf config.ConfigFeed) []*messenger.Messenger {
+	hooks := make([]*messenger.Messenger, 0)
+	for _, name := range cf.Webhooks {
+		wh, ok := d.messengers.Load(name)
+		if !ok {
+			panic("expected webhook not found: " + name)
+		}
+		hooks = append(hooks, wh)
+	}
+	return hooks
+}
+
 // processFeed checks a feed for new items and hands them over to configured messengers.
 func (d *Dispatcher) processFeed(cf config.ConfigFeed, hooks []*messenger.Messenger) error {
-	myLog := slog.With("feed", cf.Name)
-	feed, err := d.fp.ParseURL(cf.URL)
+	d.lastPolled.Store(cf.Name, d.clock.Now())
+	feed, hub, err := d.fetchFeedWithHub(cf)
 	if err != nil {
 		return fmt.Errorf("parse URL for feed %s: %w ", cf.Name, err)
 	}
-	oldest := time.Duration(d.cfg.App.Oldest) * time.Second
-	sort.Sort(feed)
-	for _, item := range feed.Items {
-		if item.Content == "" && item.Description == "" {
-			continue
+	if hub.URL != "" {
+		if err := d.subscriber.EnsureSubscription(cf.Name, hub); err != nil {
+			slog.Warn("Failed to subscribe to WebSub hub", "feed", cf.Name, "hub", hub.URL, "error", err)
 		}
+	}
+	return d.processItems(cf, feed, hooks)
+}
+
+// processItems hands over new items of a feed to configured messengers.
+func (d *Dispatcher) processItems(cf config.ConfigFeed, feed *gofeed.Feed, hooks []*messenger.Messenger) error {
+	mu, _ := d.feedLocks.LoadOrStore(cf.Name, new(sync.Mutex))
+	mu.Lock()
+	defer mu.Unlock()
+	myLog := slog.With("feed", cf.Name)
+	sortItems(feed.Items)
+	if n, err := d.st.MigrateItemIDs(cf, feed.Items); err != nil {
+		myLog.Error("Failed to migrate item IDs", "error", err)
+	} else if n > 0 {
+		myLog.Info("Migrated item IDs", "count", n)
+	}
+	if r := d.cfg.FeedRetention(cf); r.MaxItems > 0 && len(feed.Items) > r.MaxItems {
+		myLog.Warn("Feed has more items then retained. Items may be sent again.", "items", len(feed.Items), "maxItems", r.MaxItems)
+	}
+	// only fetched when there are items to post
+	iconColor := sync.OnceValue(func() int {
+		return d.feedIconColor(cf, feed)
+	})
+	for _, item := range feed.Items {
 		select {
 		case <-d.shutdown:
 			return errUserAborted
 		default:
 		}
-		if oldest != 0 && item.PublishedParsed != nil && item.PublishedParsed.Before(d.clock.Now().Add(-oldest)) {
+		if d.itemSkipReason(feed, item) != "" {
 			continue
 		}
-		state, err := d.st.GetItemState(cf, item)
+		state, err := d.st.GetItemState(cf, item, d.clock.Now())
 		if err != nil {
 			slog.Warn("Failed to read item state from DB. Assuming item is new.", "title", item.Title)
 			state = app.StateNew
 		} else if state == app.StateProcessed {
 			continue
 		}
+		enriched := d.enrichItem(cf, item)
 		for _, hook := range hooks {
-			if err := hook.AddMessage(cf.Name, feed, item, state == app.StateUpdated); err != nil {
+			fi := messenger.NewFeedItem(cf.Name, feed, enriched, state == app.StateUpdated)
+			fi.IconColor = iconColor()
+			isDuplicate, err := d.addFeedItem(cf, hook, item, fi)
+			if isDuplicate {
+				myLog.Info("Skipped duplicate item", "hook", hook.Name(), "title", item.Title)
+				continue
+			}
+			if err != nil {
 				myLog.Error("Failed to add item to webhook queue", "hook", hook.Name(), "error", err)
 				if err := d.st.UpdateFeedStats(cf.Name, func(fs *app.FeedStats) error {
 					fs.ErrorCount++
@@ -210,7 +383,7 @@ func (d *Dispatcher) processFeed(cf config.ConfigFeed, hooks []*messenger.Messen
 				continue
 			}
 		}
-		if err := d.st.RecordItem(cf, item); err != nil {
+		if err := d.st.RecordItem(cf, item, d.clock.Now()); err != nil {
 			return fmt.Errorf("record item: %w", err)
 		}
 		if err := d.st.UpdateFeedStats(cf.Name, func(fs *app.FeedStats) error {
@@ -222,8 +395,325 @@ func (d *Dispatcher) processFeed(cf config.ConfigFeed, hooks []*messenger.Messen
 		}
 		myLog.Info("Received item", "title", item.Title)
 	}
-	err = d.st.CullItems(cf, 1000)
-	return err
+	return d.cullItems(cf, feed.Items)
+}
+
+// cullItems deletes processed items of a feed, which are outside it's retention.
+// Items still in the current feed are kept regardless of their age.
+func (d *Dispatcher) cullItems(cf config.ConfigFeed, current []*gofeed.Item) error {
+	r := d.cfg.FeedRetention(cf)
+	var before time.Time
+	if r.MaxAge > 0 {
+		before = d.clock.Now().Add(-time.Duration(r.MaxAge) * time.Second)
+	}
+	hasMore, err := d.st.CullItems(cf, r.MaxItems, before, current)
+	if err != nil {
+		return err
+	}
+	if hasMore {
+		slog.Debug("More items to cull in next cycle", "feed", cf.Name)
+	}
+	return nil
+}
+
+// feedIconColor returns the average color of the feed's icon,
+// when embed colors are derived from it for the feed or any of it's webhooks.
+// Returns 0 otherwise.
+func (d *Dispatcher) feedIconColor(cf config.ConfigFeed, feed *gofeed.Feed) int {
+	if feed.Image == nil || feed.Image.URL == "" {
+		return 0
+	}
+	uses := cf.Color == config.ColorIcon
+	if cf.Color == "" {
+		for _, wh := range d.cfg.Webhooks {
+			if slices.Contains(cf.Webhooks, wh.Name) && wh.Color == config.ColorIcon {
+				uses = true
+				break
+			}
+		}
+	}
+	if !uses {
+		return 0
+	}
+	c, err := d.iconColors.Color(feed.Image.URL)
+	if err != nil {
+		slog.Warn("Failed to fetch feed icon", "feed", cf.Name, "icon", feed.Image.URL, "error", err)
+		return 0
+	}
+	return c
+}
+
+// transformItem returns a copy of an item with the transformers of a feed applied.
+// Returns the original item when a feed has no transformers or they fail.
+func (d *Dispatcher) transformItem(cf config.ConfigFeed, item *gofeed.Item) *gofeed.Item {
+	c, ok := d.transformers[cf.Name]
+	if !ok {
+		return item
+	}
+	x, err := c.Apply(item)
+	if err != nil {
+		slog.Warn("Failed to transform item. Using original item.", "feed", cf.Name, "title", item.Title, "error", err)
+		return item
+	}
+	return x
+}
+
+// translateItem returns a copy of an item with translated title and description,
+// when translation is enabled for a feed. Translations are cached.
+// Returns the original item when translation is disabled or fails.
+func (d *Dispatcher) translateItem(cf config.ConfigFeed, item *gofeed.Item) *gofeed.Item {
+	if cf.Translate == nil || d.translator == nil {
+		return item
+	}
+	target := cf.Translate.Target
+	t, err := d.st.GetTranslation(cf, item, target)
+	if errors.Is(err, storage.ErrNotFound) {
+		if !d.isTranslatorAvailable() {
+			slog.Info("Translator unavailable. Using original text.", "feed", cf.Name, "title", item.Title)
+			return item
+		}
+		t.Title, err = d.translator.Translate(item.Title, cf.Translate.Source, target, translator.FormatText)
+		if err == nil {
+			t.Description, err = d.translator.Translate(itemDescription(item), cf.Translate.Source, target, translator.FormatHTML)
+		}
+		if err != nil {
+			d.setTranslatorFailed()
+			slog.Warn("Failed to translate item. Using original text.", "feed", cf.Name, "title", item.Title, "error", err)
+			return item
+		}
+		if err := d.st.SaveTranslation(cf, item, target, t.Title, t.Description); err != nil {
+			slog.Error("Failed to save translation", "feed", cf.Name, "title", item.Title, "error", err)
+		}
+	} else if err != nil {
+		slog.Error("Failed to read translation", "feed", cf.Name, "title", item.Title, "error", err)
+		return item
+	}
+	x := *item
+	if t.Title != "" {
+		x.Title = t.Title
+	}
+	if t.Description != "" {
+		if x.Content != "" {
+			x.Content = t.Description
+		} else {
+			x.Description = t.Description
+		}
+	}
+	return &x
+}
+
+// isTranslatorAvailable reports wether the translator should be used.
+func (d *Dispatcher) isTranslatorAvailable() bool {
+	d.translatorMu.Lock()
+	defer d.translatorMu.Unlock()
+	return !d.clock.Now().Before(d.translatorRetry)
+}
+
+// setTranslatorFailed skips translations for one ticker interval,
+// so that an unavailable translator does not stall processing of feeds.
+func (d *Dispatcher) setTranslatorFailed() {
+	d.translatorMu.Lock()
+	defer d.translatorMu.Unlock()
+	d.translatorRetry = d.clock.Now().Add(time.Duration(max(d.cfg.App.Ticker, 1)) * time.Second)
+}
+
+// itemDescription returns the description of an item as shown in messages.
+func itemDescription(item *gofeed.Item) string {
+	if item.Content != "" {
+		return item.Content
+	}
+	return item.Description
+}
+
+// enrichItem returns an item prepared for posting with the Open Graph image added,
+// translated and transformed as configured for the feed.
+// Items are translated first, so that translations are cached under the ID of the recorded item.
+func (d *Dispatcher) enrichItem(cf config.ConfigFeed, item *gofeed.Item) *gofeed.Item {
+	if cf.OGImage {
+		d.addOGImage(item)
+	}
+	return d.transformItem(cf, d.translateItem(cf, item))
+}
+
+// addOGImage adds the Open Graph image of the linked article to an item without image.
+func (d *Dispatcher) addOGImage(item *gofeed.Item) {
+	if item.Link == "" || messenger.ItemImageURL(item) != "" {
+		return
+	}
+	u, err := d.ogImages.ImageURL(item.Link)
+	if err != nil {
+		slog.Warn("Failed to fetch Open Graph image", "link", item.Link, "error", err)
+		return
+	}
+	if u != "" {
+		item.Image = &gofeed.Image{URL: u}
+	}
+}
+
+// addFeedItem adds an item to the queue of a webhook, unless it was already delivered
+// to that webhook from another feed. Reports wether the item was a duplicate.
+// Items are only recorded as delivered after they have been queued successfully.
+func (d *Dispatcher) addFeedItem(cf config.ConfigFeed, hook *messenger.Messenger, item *gofeed.Item, fi messenger.FeedItem) (bool, error) {
+	cd, ok := d.dedups[hook.Name()]
+	if !ok {
+		return false, hook.AddFeedItem(fi)
+	}
+	key := dedupKey(cd, item)
+	if key == "" {
+		return false, hook.AddFeedItem(fi)
+	}
+	d.dedupMu.Lock()
+	defer d.dedupMu.Unlock()
+	window := time.Duration(cd.Window) * time.Second
+	isDuplicate, err := d.st.IsDuplicate(hook.Name(), key, cf.Name, d.clock.Now(), window)
+	if err != nil {
+		slog.Error("Failed to check item for duplicate. Assuming it is new.", "hook", hook.Name(), "error", err)
+	} else if isDuplicate {
+		return true, nil
+	}
+	if err := hook.AddFeedItem(fi); err != nil {
+		return false, err
+	}
+	if err := d.st.MarkDelivered(hook.Name(), key, cf.Name, d.clock.Now()); err != nil {
+		slog.Error("Failed to record delivered item", "hook", hook.Name(), "error", err)
+	}
+	return false, nil
+}
+
+// cullDelivered deletes records of delivered items, which are outside the dedup window.
+func (d *Dispatcher) cullDelivered() {
+	for name, cd := range d.dedups {
+		before := d.clock.Now().Add(-time.Duration(cd.Window) * time.Second)
+		if err := d.st.CullDelivered(name, before); err != nil {
+			slog.Error("Failed to cull delivered items", "hook", name, "error", err)
+		}
+	}
+}
+
+// isPushedFeed reports wether a feed has an active WebSub subscription
+// and does not yet need to be polled again.
+func (d *Dispatcher) isPushedFeed(cf config.ConfigFeed) bool {
+	if d.subscriber == nil || !d.subscriber.IsActive(cf.Name) {
+		return false
+	}
+	last, ok := d.lastPolled.Load(cf.Name)
+	if !ok {
+		return false
+	}
+	fallback := time.Duration(d.cfg.App.WebSub.Fallback) * time.Second
+	return d.clock.Now().Before(last.Add(fallback))
+}
+
+// processPushedFeed processes the content of a feed pushed by a WebSub hub.
+func (d *Dispatcher) processPushedFeed(feedName string, feed *gofeed.Feed) {
+	d.mu.Lock()
+	isRunning := d.isRunning
+	d.mu.Unlock()
+	if !isRunning {
+		slog.Warn("Discarding pushed content while dispatcher is not running", "feed", feedName)
+		return
+	}
+	i := slices.IndexFunc(d.cfg.EnabledFeeds(), func(x config.ConfigFeed) bool {
+		return x.Name == feedName
+	})
+	if i == -1 {
+		slog.Warn("Discarding pushed content for unknown feed", "feed", feedName)
+		return
+	}
+	cf := d.cfg.EnabledFeeds()[i]
+	if paused, err := d.st.IsFeedPaused(cf.Name); err != nil {
+		slog.Error("Failed to read paused state for feed", "feed", cf.Name, "error", err)
+		return
+	} else if paused {
+		slog.Debug("Discarding pushed content for paused feed", "feed", cf.Name)
+		return
+	}
+	slog.Debug("Received pushed content", "feed", cf.Name, "items", len(feed.Items))
+	if err := d.processItems(cf, feed, d.feedMessengers(cf)); err != nil && err != errUserAborted {
+		slog.Error("Failed to process pushed content", "feed", cf.Name, "error", err)
+	}
+}
+
+// WebSubHandler returns the handler for the WebSub callback endpoint
+// or nil if WebSub is not enabled or in dry-run mode.
+func (d *Dispatcher) WebSubHandler() http.Handler {
+	if d.subscriber == nil {
+		return nil
+	}
+	return d.subscriber
+}
+
+// waitJitter waits for a random duration up to the configured jitter,
+// so that fetching of feeds is spread across the ticker interval.
+// Reports wether the dispatcher was shut down while waiting.
+func (d *Dispatcher) waitJitter() bool {
+	if d.cfg.App.FetchJitter <= 0 {
+		return false
+	}
+	wait := rand.N(time.Duration(d.cfg.App.FetchJitter) * time.Second)
+	select {
+	case <-d.shutdown:
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
+
+// updateFeedHealth records the result of processing a feed.
+// Failing feeds are polled less frequently with an exponential backoff.
+func (d *Dispatcher) updateFeedHealth(cf config.ConfigFeed, processErr error) {
+	ticker := time.Duration(d.cfg.App.Ticker) * time.Second
+	backoffMax := time.Duration(d.cfg.App.BackoffMax) * time.Second
+	if err := d.st.UpdateFeedStats(cf.Name, func(fs *app.FeedStats) error {
+		if processErr == nil {
+			fs.ConsecutiveErrors = 0
+			fs.NextPoll = time.Time{}
+			return nil
+		}
+		fs.ErrorCount++
+		fs.ConsecutiveErrors++
+		wait := feedBackoff(fs.ConsecutiveErrors, ticker, backoffMax)
+		var errFetch fetchError
+		if errors.As(processErr, &errFetch) && errFetch.RetryAfter > wait {
+			wait = errFetch.RetryAfter
+		}
+		fs.NextPoll = d.clock.Now().Add(wait)
+		slog.Error("Failed to process feed", "feed", cf.Name, "error", processErr, "consecutiveErrors", fs.ConsecutiveErrors, "nextPoll", fs.NextPoll)
+		return nil
+	}); err != nil {
+		slog.Error("failed to update feed stats", "feed", cf.Name, "error", err)
+	}
+}
+
+// sortItems sorts items by publishing date, oldest first.
+// Undated items keep their order and come first, since gofeed's sort would panic on them.
+func sortItems(items []*gofeed.Item) {
+	slices.SortStableFunc(items, func(a, b *gofeed.Item) int {
+		switch {
+		case a.PublishedParsed == nil && b.PublishedParsed == nil:
+			return 0
+		case a.PublishedParsed == nil:
+			return -1
+		case b.PublishedParsed == nil:
+			return 1
+		}
+		return a.PublishedParsed.Compare(*b.PublishedParsed)
+	})
+}
+
+// itemSkipReason returns the reason why an item should not be forwarded
+// or an empty string if it should be forwarded.
+// Items of scraped pages and JSON endpoints can consist of a title only.
+func (d *Dispatcher) itemSkipReason(feed *gofeed.Feed, item *gofeed.Item) string {
+	titleOnly := feed.FeedType == config.FeedTypeScrape || feed.FeedType == config.FeedTypeJSONAPI
+	if item.Content == "" && item.Description == "" && (!titleOnly || item.Title == "") {
+		return "item has no content"
+	}
+	oldest := time.Duration(d.cfg.App.Oldest) * time.Second
+	if oldest != 0 && item.PublishedParsed != nil && item.PublishedParsed.Before(d.clock.Now().Add(-oldest)) {
+		return fmt.Sprintf("item is older then %s", oldest)
+	}
+	return ""
 }
 
 // MessengerStatus returns the current status of a messenger.
@@ -236,6 +726,38 @@ func (d *Dispatcher) MessengerStatus(webhookName string) (messenger.Status, erro
 	return wh.Status(), nil
 }
 
+// SetFeedPaused pauses or resumes a feed. Paused feeds are not checked for new items.
+func (d *Dispatcher) SetFeedPaused(feedName string, paused bool) error {
+	if !slices.ContainsFunc(d.cfg.Feeds, func(x config.ConfigFeed) bool {
+		return x.Name == feedName
+	}) {
+		return fmt.Errorf("feed \"%s\": %w", feedName, ErrNotFound)
+	}
+	if err := d.st.SetFeedPaused(feedName, paused); err != nil {
+		return err
+	}
+	slog.Info("Feed paused state changed", "feed", feedName, "paused", paused)
+	return nil
+}
+
+// SetWebhookPaused pauses or resumes a webhook.
+// Messages for a paused webhook are queued, but not sent.
+func (d *Dispatcher) SetWebhookPaused(webhookName string, paused bool) error {
+	if !slices.ContainsFunc(d.cfg.Webhooks, func(x config.ConfigWebhook) bool {
+		return x.Name == webhookName
+	}) {
+		return fmt.Errorf("webhook \"%s\": %w", webhookName, ErrNotFound)
+	}
+	if err := d.st.SetWebhookPaused(webhookName, paused); err != nil {
+		return err
+	}
+	if mg, ok := d.messengers.Load(webhookName); ok {
+		mg.SetPaused(paused)
+	}
+	slog.Info("Webhook paused state changed", "webhook", webhookName, "paused", paused)
+	return nil
+}
+
 func (d *Dispatcher) PostLatestFeedItem(feedName string) error {
 	var cf config.ConfigFeed
 	for _, f := range d.cfg.Feeds {
@@ -258,7 +780,7 @@ func (d *Dispatcher) PostLatestFeedItem(feedName string) error {
 	if len(hooks) == 0 {
 		return fmt.Errorf("no webhooks configured for feed: %s ", feedName)
 	}
-	feed, err := d.fp.ParseURL(cf.URL)
+	feed, err := d.fetchFeed(cf)
 	if err != nil {
 		return fmt.Errorf("parse URL for feed: %w ", err)
 	}
@@ -274,18 +796,25 @@ func (d *Dispatcher) PostLatestFeedItem(feedName string) error {
 	latest := slices.MaxFunc(items, func(a, b *gofeed.Item) int {
 		return a.PublishedParsed.Compare(*b.PublishedParsed)
 	})
-	fi := messenger.NewFeedItem(feedName, feed, latest, false)
-	m, err := fi.ToDiscordMessage(false)
-	if err != nil {
-		return fmt.Errorf("convert item to Discord message: %w", err)
-	}
-	if err := m.Validate(); err != nil {
-		return fmt.Errorf("convert item to Discord message: %w", err)
-	}
+	fi := messenger.NewFeedItem(feedName, feed, d.enrichItem(cf, latest), false)
+	fi.IconColor = d.feedIconColor(cf, feed)
 	for _, hook := range hooks {
-		wh := d.client.NewWebhook(hook.URL)
-		if _, err := wh.Execute(m, nil); err != nil {
-			return fmt.Errorf("post item to webhook: %w", err)
+		opts := messenger.NewRenderOptions(d.cfg, feedName, hook.Name)
+		opts.BrandingDisabled = false
+		dms, _, err := fi.ToDiscordMessages(opts)
+		if err != nil {
+			return fmt.Errorf("convert item to Discord message: %w", err)
+		}
+		for _, m := range dms {
+			if err := m.Validate(); err != nil {
+				return fmt.Errorf("convert item to Discord message: %w", err)
+			}
+		}
+		wh := d.WebhookClient(hook.Name).NewWebhook(messenger.WebhookURL(hook.URL, opts.ThreadID, len(opts.Buttons) > 0))
+		for _, m := range dms {
+			if err := messenger.ExecuteOrRecord(d.cfg, wh, hook.Name, m); err != nil {
+				return fmt.Errorf("post item to webhook: %w", err)
+			}
 		}
 	}
 	return nil
@@ -0,0 +1,239 @@
+package dispatcher
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ErikKalkoken/go-dhook"
+	"github.com/jarcoal/httpmock"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/messenger"
+	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+	"github.com/ErikKalkoken/feedhook/internal/pqueue"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestProcessItems(t *testing.T) {
+	run := func(t *testing.T, cfg config.Config, page string) (*pqueue.PQueue, *storage.Storage) {
+		cfg.Webhooks = []config.ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/hook"}}
+		cf := cfg.Feeds[0]
+		db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+		if err != nil {
+			t.Fatalf("Failed to open DB: %s", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		st := storage.New(db, cfg)
+		if err := st.Init(); err != nil {
+			t.Fatalf("Failed to init: %s", err)
+		}
+		d, err := New(st, cfg, fakeClock{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := pqueue.New(db, "hook1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hook := messenger.NewMessenger(dhook.NewClient(), q, "hook1", "https://www.example.com/hook", st, cfg)
+		feed, err := d.parseFeed(cf, strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.processItems(cf, feed, []*messenger.Messenger{hook}); err != nil {
+			t.Fatal(err)
+		}
+		return q, st
+	}
+	t.Run("should post title-only items of scrape feeds", func(t *testing.T) {
+		cf := config.ConfigFeed{
+			Name:     "feed1",
+			URL:      "https://www.example.com/changelog",
+			Type:     config.FeedTypeScrape,
+			Scrape:   &config.ConfigScrape{Item: "li", Title: "a", Link: "a"},
+			Webhooks: []string{"hook1"},
+		}
+		page := `<ul><li><a href="/v2">Version 2</a></li><li><a href="/v1">Version 1</a></li></ul>`
+		q, st := run(t, config.Config{Feeds: []config.ConfigFeed{cf}}, page)
+		assert.Equal(t, 2, q.Size())
+		assert.Equal(t, 2, st.ItemCount(cf))
+	})
+	t.Run("should post title-only items of JSON API feeds", func(t *testing.T) {
+		cf := config.ConfigFeed{
+			Name:     "feed1",
+			URL:      "https://www.example.com/api",
+			Type:     config.FeedTypeJSONAPI,
+			JSONAPI:  &config.ConfigJSONAPI{Title: "title", Link: "url"},
+			Webhooks: []string{"hook1"},
+		}
+		page := `[{"title": "Outage", "url": "/1"}, {"name": "invalid"}]`
+		q, st := run(t, config.Config{Feeds: []config.ConfigFeed{cf}}, page)
+		assert.Equal(t, 1, q.Size())
+		assert.Equal(t, 1, st.ItemCount(cf))
+	})
+	t.Run("should cache translations under the ID of the original item", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder(
+			"POST",
+			"http://localhost:5000/translate",
+			httpmock.NewJsonResponderOrPanic(200, map[string]string{"translatedText": "Hello"}),
+		)
+		cf := config.ConfigFeed{
+			Name:      "feed1",
+			URL:       "https://www.example.com/api",
+			Type:      config.FeedTypeJSONAPI,
+			JSONAPI:   &config.ConfigJSONAPI{Title: "title", Link: "url"},
+			ItemID:    config.ItemIDLink,
+			Transform: []config.ConfigTransformer{{Type: config.TransformStripTracking}},
+			Translate: &config.ConfigTranslate{Source: "auto", Target: "en"},
+			Webhooks:  []string{"hook1"},
+		}
+		cfg := config.Config{
+			App:   config.ConfigApp{Translator: config.ConfigTranslator{URL: "http://localhost:5000/translate", Timeout: 5}},
+			Feeds: []config.ConfigFeed{cf},
+		}
+		page := `[{"title": "Hallo", "url": "https://www.example.com/a?utm_source=rss"}]`
+		_, st := run(t, cfg, page)
+		item := &gofeed.Item{Title: "Hallo", Link: "https://www.example.com/a?utm_source=rss", GUID: "https://www.example.com/a?utm_source=rss"}
+		x, err := st.GetTranslation(cf, item, "en")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Hello", x.Title)
+		}
+	})
+	t.Run("should stop translating after translator failed", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "http://localhost:5000/translate", httpmock.NewStringResponder(500, ""))
+		cf := config.ConfigFeed{
+			Name:      "feed1",
+			URL:       "https://www.example.com/api",
+			Type:      config.FeedTypeJSONAPI,
+			JSONAPI:   &config.ConfigJSONAPI{Title: "title"},
+			Translate: &config.ConfigTranslate{Source: "auto", Target: "en"},
+			Webhooks:  []string{"hook1"},
+		}
+		cfg := config.Config{
+			App:   config.ConfigApp{Ticker: 30, Translator: config.ConfigTranslator{URL: "http://localhost:5000/translate", Timeout: 5}},
+			Feeds: []config.ConfigFeed{cf},
+		}
+		page := `[{"title": "Alpha"}, {"title": "Bravo"}, {"title": "Charlie"}]`
+		q, _ := run(t, cfg, page)
+		assert.Equal(t, 3, q.Size())
+		assert.Equal(t, 1, httpmock.GetCallCountInfo()["POST http://localhost:5000/translate"])
+	})
+}
+
+func TestEnrichItem(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		"GET",
+		"https://www.example.com/article",
+		httpmock.NewStringResponder(200, `<html><head><meta property="og:image" content="https://www.example.com/image.jpg"></head></html>`),
+	)
+	cf := config.ConfigFeed{
+		Name:      "feed1",
+		OGImage:   true,
+		Transform: []config.ConfigTransformer{{Type: config.TransformRegexReplace, Pattern: "^Breaking: "}},
+	}
+	d, err := New(nil, config.Config{Feeds: []config.ConfigFeed{cf}}, fakeClock{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("should add Open Graph image and transform item", func(t *testing.T) {
+		item := &gofeed.Item{Title: "Breaking: Title", Link: "https://www.example.com/article"}
+		got := d.enrichItem(cf, item)
+		assert.Equal(t, "Title", got.Title)
+		if assert.NotNil(t, got.Image) {
+			assert.Equal(t, "https://www.example.com/image.jpg", got.Image.URL)
+		}
+	})
+}
+
+func TestWebSubHandler(t *testing.T) {
+	ws := config.ConfigWebSub{CallbackURL: "https://feedhook.example.com/websub", Listen: ":8080"}
+	t.Run("should return handler when WebSub is enabled", func(t *testing.T) {
+		d, err := New(nil, config.Config{App: config.ConfigApp{WebSub: ws}}, nil)
+		if assert.NoError(t, err) {
+			assert.NotNil(t, d.WebSubHandler())
+		}
+	})
+	t.Run("should not subscribe in dry-run mode", func(t *testing.T) {
+		d, err := New(nil, config.Config{App: config.ConfigApp{WebSub: ws, DryRun: true}}, nil)
+		if assert.NoError(t, err) {
+			assert.Nil(t, d.WebSubHandler())
+		}
+	})
+}
+
+func TestAddFeedItem(t *testing.T) {
+	now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+	cfg := config.Config{
+		Webhooks: []config.ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/hook", Dedup: &config.ConfigDedup{Window: 3600}}},
+	}
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	st := storage.New(db, cfg)
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	d, err := New(st, cfg, fakeClock{now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf1 := config.ConfigFeed{Name: "feed1"}
+	cf2 := config.ConfigFeed{Name: "feed2"}
+	feed := &gofeed.Feed{Title: "title"}
+	t.Run("should skip item already delivered from another feed", func(t *testing.T) {
+		q, err := pqueue.New(db, "hook1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hook := messenger.NewMessenger(dhook.NewClient(), q, "hook1", "https://www.example.com/hook", st, cfg)
+		item := &gofeed.Item{Title: "title", Link: "https://www.example.com/a"}
+		isDuplicate, err := d.addFeedItem(cf1, hook, item, messenger.NewFeedItem(cf1.Name, feed, item, false))
+		if assert.NoError(t, err) {
+			assert.False(t, isDuplicate)
+		}
+		isDuplicate, err = d.addFeedItem(cf2, hook, item, messenger.NewFeedItem(cf2.Name, feed, item, false))
+		if assert.NoError(t, err) {
+			assert.True(t, isDuplicate)
+		}
+		assert.Equal(t, 1, q.Size())
+	})
+	t.Run("should not record item as delivered when it could not be queued", func(t *testing.T) {
+		db2, err := bolt.Open(filepath.Join(t.TempDir(), "queue.db"), 0600, nil)
+		if err != nil {
+			t.Fatalf("Failed to open DB: %s", err)
+		}
+		q, err := pqueue.New(db2, "hook1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db2.Close()
+		hook := messenger.NewMessenger(dhook.NewClient(), q, "hook1", "https://www.example.com/hook", st, cfg)
+		item := &gofeed.Item{Title: "title", Link: "https://www.example.com/b"}
+		_, err = d.addFeedItem(cf1, hook, item, messenger.NewFeedItem(cf1.Name, feed, item, false))
+		assert.Error(t, err)
+		isDuplicate, err := st.IsDuplicate("hook1", dedupKey(config.ConfigDedup{}, item), cf2.Name, now, time.Hour)
+		if assert.NoError(t, err) {
+			assert.False(t, isDuplicate)
+		}
+	})
+}
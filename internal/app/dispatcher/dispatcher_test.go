@@ -1,6 +1,8 @@
 package dispatcher_test
 
 import (
+	"io"
+	"net/http"
 	"path/filepath"
 	"testing"
 	"time"
@@ -52,7 +54,10 @@ func TestService(t *testing.T) {
 			"https://www.example.com/hook",
 			httpmock.NewStringResponder(204, ""),
 		)
-		d := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		d, err := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
 		if err := d.Start(); err != nil {
 			t.Fatal(err)
 		}
@@ -66,6 +71,32 @@ func TestService(t *testing.T) {
 			assert.Equal(t, 1, fs.ReceivedCount)
 		}
 	})
+	t.Run("should back off polling a failing feed and honor retry after", func(t *testing.T) {
+		if err := st.ClearFeedStats(); err != nil {
+			t.Fatal(err)
+		}
+		httpmock.Reset()
+		resp := httpmock.NewStringResponse(503, "")
+		resp.Header.Set("Retry-After", "600")
+		httpmock.RegisterResponder("GET", "https://www.example.com/feed", httpmock.ResponderFromResponse(resp))
+		now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+		d, err := dispatcher.New(st, cfg, fakeTime{now: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Start(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Second)
+		d.Stop()
+		info := httpmock.GetCallCountInfo()
+		assert.Equal(t, 1, info["GET https://www.example.com/feed"])
+		fs, err := st.GetFeedStats("feed1")
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, fs.ConsecutiveErrors)
+			assert.Equal(t, now.Add(600*time.Second), fs.NextPoll)
+		}
+	})
 	t.Run("should return error when trying to start an already running dispatcher", func(t *testing.T) {
 		httpmock.Reset()
 		httpmock.RegisterResponder(
@@ -78,11 +109,14 @@ func TestService(t *testing.T) {
 			"https://www.example.com/hook",
 			httpmock.NewStringResponder(204, ""),
 		)
-		d := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		d, err := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
 		if err := d.Start(); err != nil {
 			t.Fatal(err)
 		}
-		err := d.Start()
+		err = d.Start()
 		assert.Error(t, err)
 		d.Stop()
 		err = d.Start()
@@ -102,12 +136,229 @@ func TestService(t *testing.T) {
 			"https://www.example.com/hook",
 			httpmock.NewStringResponder(204, ""),
 		)
-		d := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		d, err := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
 		if err := d.Start(); err != nil {
 			t.Fatal(err)
 		}
-		err := d.Restart()
+		err = d.Restart()
 		assert.NoError(t, err)
 		assert.True(t, d.Stop())
 	})
+	t.Run("can preview items of a feed without posting them", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/feed",
+			httpmock.NewXmlResponderOrPanic(200, httpmock.File("testdata/atomfeed.xml")),
+		)
+		d, err := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		title, previews, err := d.PreviewFeed("https://www.example.com/feed", 5)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "EVE Online Status - Incident History", title)
+			assert.Len(t, previews, 1)
+			assert.Equal(t, "", previews[0].SkipReason)
+			assert.Equal(t, "", previews[0].ValidationError)
+			assert.Contains(t, previews[0].Payload, "Intermittent AIR Daily Goals")
+		}
+		assert.Equal(t, 0, httpmock.GetCallCountInfo()["POST https://www.example.com/hook"])
+	})
+	t.Run("should report items skipped by the oldest rule in preview", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/feed",
+			httpmock.NewXmlResponderOrPanic(200, httpmock.File("testdata/atomfeed.xml")),
+		)
+		d, err := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 9, 22, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, previews, err := d.PreviewFeed("https://www.example.com/feed", 5)
+		if assert.NoError(t, err) {
+			assert.NotEqual(t, "", previews[0].SkipReason)
+		}
+	})
+}
+
+func TestDedup(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	cfg := config.Config{
+		App: config.ConfigApp{Oldest: 3600 * 24, Ticker: 1},
+		Webhooks: []config.ConfigWebhook{{
+			Name:  "hook1",
+			URL:   "https://www.example.com/hook",
+			Dedup: &config.ConfigDedup{Key: config.DedupKeyLink, Window: 3600},
+		}},
+		Feeds: []config.ConfigFeed{
+			{Name: "feed1", URL: "https://www.example.com/feed1", Webhooks: []string{"hook1"}},
+			{Name: "feed2", URL: "https://www.example.com/feed2", Webhooks: []string{"hook1"}},
+		},
+	}
+	st := storage.New(db, cfg)
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	t.Run("should send item carried by several feeds only once to a webhook", func(t *testing.T) {
+		httpmock.Reset()
+		for _, u := range []string{"https://www.example.com/feed1", "https://www.example.com/feed2"} {
+			httpmock.RegisterResponder(
+				"GET",
+				u,
+				httpmock.NewXmlResponderOrPanic(200, httpmock.File("testdata/atomfeed.xml")),
+			)
+		}
+		httpmock.RegisterResponder(
+			"POST",
+			"https://www.example.com/hook",
+			httpmock.NewStringResponder(204, ""),
+		)
+		d, err := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Start(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Second)
+		d.Stop()
+		info := httpmock.GetCallCountInfo()
+		assert.Equal(t, 1, info["POST https://www.example.com/hook"])
+		for _, name := range []string{"feed1", "feed2"} {
+			fs, err := st.GetFeedStats(name)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 1, fs.ReceivedCount)
+			}
+		}
+	})
+}
+
+func TestTranslation(t *testing.T) {
+	cfg := config.Config{
+		App: config.ConfigApp{
+			Oldest:     3600 * 24,
+			Ticker:     1,
+			Translator: config.ConfigTranslator{URL: "http://localhost:5000/translate", Timeout: 5},
+		},
+		Webhooks: []config.ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/hook"}},
+		Feeds: []config.ConfigFeed{{
+			Name:      "feed1",
+			URL:       "https://www.example.com/feed1",
+			Webhooks:  []string{"hook1"},
+			Translate: &config.ConfigTranslate{Source: "auto", Target: "de"},
+		}},
+	}
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	run := func(t *testing.T, translator httpmock.Responder) string {
+		p := filepath.Join(t.TempDir(), "test.db")
+		db, err := bolt.Open(p, 0600, nil)
+		if err != nil {
+			t.Fatalf("Failed to open DB: %s", err)
+		}
+		defer db.Close()
+		st := storage.New(db, cfg)
+		if err := st.Init(); err != nil {
+			t.Fatalf("Failed to init: %s", err)
+		}
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/feed1",
+			httpmock.NewXmlResponderOrPanic(200, httpmock.File("testdata/atomfeed.xml")),
+		)
+		httpmock.RegisterResponder("POST", "http://localhost:5000/translate", translator)
+		var body string
+		httpmock.RegisterResponder("POST", "https://www.example.com/hook", func(req *http.Request) (*http.Response, error) {
+			b, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			body = string(b)
+			return httpmock.NewStringResponse(204, ""), nil
+		})
+		d, err := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Start(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Second)
+		d.Stop()
+		return body
+	}
+	t.Run("should post translated item", func(t *testing.T) {
+		body := run(t, httpmock.NewJsonResponderOrPanic(200, map[string]string{"translatedText": "Übersetzt"}))
+		assert.Contains(t, body, "Übersetzt")
+	})
+	t.Run("should post original item when translation fails", func(t *testing.T) {
+		body := run(t, httpmock.NewStringResponder(500, ""))
+		assert.Contains(t, body, "Intermittent AIR Daily Goals")
+	})
+}
+
+func TestTransform(t *testing.T) {
+	cfg := config.Config{
+		App:      config.ConfigApp{Oldest: 3600 * 24, Ticker: 1},
+		Webhooks: []config.ConfigWebhook{{Name: "hook1", URL: "https://www.example.com/hook"}},
+		Feeds: []config.ConfigFeed{{
+			Name:     "feed1",
+			URL:      "https://www.example.com/feed1",
+			Webhooks: []string{"hook1"},
+			Transform: []config.ConfigTransformer{
+				{Type: config.TransformRegexReplace, Pattern: "^Intermittent", Replace: "Sporadic"},
+				{Type: config.TransformRewriteDomain, From: "status.eveonline.com", To: "status.example.com"},
+			},
+		}},
+	}
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	st := storage.New(db, cfg)
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		"GET",
+		"https://www.example.com/feed1",
+		httpmock.NewXmlResponderOrPanic(200, httpmock.File("testdata/atomfeed.xml")),
+	)
+	var body string
+	httpmock.RegisterResponder("POST", "https://www.example.com/hook", func(req *http.Request) (*http.Response, error) {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+		return httpmock.NewStringResponse(204, ""), nil
+	})
+	d, err := dispatcher.New(st, cfg, fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Second)
+	d.Stop()
+	assert.Contains(t, body, "Sporadic AIR Daily Goals")
+	assert.Contains(t, body, "https://status.example.com/incidents/j1zbcqgx33b6")
 }
@@ -0,0 +1,196 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/websub"
+)
+
+// fetchError represents a failed HTTP request for a feed.
+type fetchError struct {
+	StatusCode int
+	RetryAfter time.Duration // requested by the publisher. Zero when not requested.
+}
+
+func (e fetchError) Error() string {
+	return fmt.Sprintf("http error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// fetchFeed fetches a feed from it's URL and returns it parsed.
+// Concurrent fetches are limited, both in total and per host.
+func (d *Dispatcher) fetchFeed(cf config.ConfigFeed) (*gofeed.Feed, error) {
+	feed, _, err := d.fetchFeedWithHub(cf)
+	return feed, err
+}
+
+// fetchFeedWithHub fetches a feed from it's source and returns it parsed.
+// Also returns the WebSub hub advertised by the feed, when WebSub is enabled.
+func (d *Dispatcher) fetchFeedWithHub(cf config.ConfigFeed) (*gofeed.Feed, websub.Hub, error) {
+	if len(cf.Command) > 0 {
+		feed, err := d.runFeedCommand(cf)
+		return feed, websub.Hub{}, err
+	}
+	if strings.HasPrefix(cf.URL, "file:") {
+		feed, err := d.readFeedFile(cf)
+		return feed, websub.Hub{}, err
+	}
+	req, err := d.newFeedRequest(cf)
+	if err != nil {
+		return nil, websub.Hub{}, err
+	}
+	release := d.acquireFetchSlot(req.URL.Host)
+	defer release()
+	resp, err := d.feedHTTPClient(cf).Do(req)
+	if err != nil {
+		return nil, websub.Hub{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fetchError{StatusCode: resp.StatusCode}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			err.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), d.clock.Now())
+		}
+		return nil, websub.Hub{}, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, websub.Hub{}, err
+	}
+	feed, err := d.parseFeed(cf, bytes.NewReader(body))
+	if err != nil {
+		return nil, websub.Hub{}, err
+	}
+	var hub websub.Hub
+	if d.subscriber != nil && (cf.Type == "" || cf.Type == config.FeedTypeFeed) {
+		hub = websub.DiscoverHub(resp.Header, body, feed)
+		if hub.Topic == "" {
+			hub.Topic = cf.URL
+		}
+	}
+	return feed, hub, nil
+}
+
+// readFeedFile reads a feed from a local file and returns it parsed.
+func (d *Dispatcher) readFeedFile(cf config.ConfigFeed) (*gofeed.Feed, error) {
+	u, err := url.Parse(cf.URL)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.FromSlash(u.Path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return d.parseFeed(cf, f)
+}
+
+// runFeedCommand runs the command of a feed and returns it's output parsed.
+func (d *Dispatcher) runFeedCommand(cf config.ConfigFeed) (*gofeed.Feed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.cfg.App.Timeout)*time.Second)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cf.Command[0], cf.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return d.parseFeed(cf, &stdout)
+}
+
+// parseFeed parses the content of a feed's source according to it's type.
+func (d *Dispatcher) parseFeed(cf config.ConfigFeed, r io.Reader) (*gofeed.Feed, error) {
+	switch cf.Type {
+	case config.FeedTypeJSONAPI:
+		return jsonAPIFeed(r, cf.URL, *cf.JSONAPI)
+	case config.FeedTypeScrape:
+		return scrapeFeed(r, cf.URL, *cf.Scrape)
+	}
+	return d.fp.Parse(r)
+}
+
+// newFeedRequest returns a new request for fetching a feed
+// with the configured headers and authentication.
+func (d *Dispatcher) newFeedRequest(cf config.ConfigFeed) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, cf.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.cfg.App.UserAgent != "" {
+		req.Header.Set("User-Agent", d.cfg.App.UserAgent)
+	} else {
+		req.Header.Set("User-Agent", d.fp.UserAgent)
+	}
+	for k, v := range cf.Headers {
+		req.Header.Set(k, v)
+	}
+	if cf.BasicAuth != nil {
+		req.SetBasicAuth(cf.BasicAuth.Username, cf.BasicAuth.Password)
+	}
+	if cf.BearerTokenFile != "" {
+		b, err := os.ReadFile(cf.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(b)))
+	}
+	return req, nil
+}
+
+// acquireFetchSlot blocks until a slot for fetching from a host is available.
+// The returned function must be called to release the slot.
+func (d *Dispatcher) acquireFetchSlot(host string) func() {
+	hs, _ := d.hostSlots.LoadOrStore(host, semaphore.NewWeighted(int64(max(d.cfg.App.MaxHostFetches, 1))))
+	ctx := context.Background()
+	hs.Acquire(ctx, 1)
+	d.fetchSlots.Acquire(ctx, 1)
+	return func() {
+		d.fetchSlots.Release(1)
+		hs.Release(1)
+	}
+}
+
+// parseRetryAfter returns the duration from a Retry-After header,
+// which can be either in seconds or a HTTP date.
+// Returns zero when the header is missing or invalid.
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	t, err := http.ParseTime(s)
+	if err != nil {
+		return 0
+	}
+	return max(t.Sub(now), 0)
+}
+
+// feedBackoff returns the wait time before the next poll of a failing feed.
+// It doubles with each consecutive error, but does not exceed a maximum.
+func feedBackoff(consecutiveErrors int, ticker, maxWait time.Duration) time.Duration {
+	wait := ticker
+	for range consecutiveErrors {
+		wait *= 2
+		if wait >= maxWait {
+			return maxWait
+		}
+	}
+	return wait
+}
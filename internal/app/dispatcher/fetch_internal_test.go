@@ -0,0 +1,178 @@
+package dispatcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"120", 120 * time.Second},
+		{"Thu, 22 Aug 2024 12:05:00 GMT", 5 * time.Minute},
+		{"Thu, 22 Aug 2024 11:05:00 GMT", 0},
+		{"", 0},
+		{"invalid", 0},
+		{"-5", 0},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, parseRetryAfter(tc.in, now))
+		})
+	}
+}
+
+func TestFeedBackoff(t *testing.T) {
+	cases := []struct {
+		errors int
+		want   time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 60 * time.Second},
+		{2, 120 * time.Second},
+		{5, 960 * time.Second},
+		{6, 1000 * time.Second},
+		{100, 1000 * time.Second},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, feedBackoff(tc.errors, 30*time.Second, 1000*time.Second))
+		})
+	}
+}
+
+func TestAcquireFetchSlot(t *testing.T) {
+	cfg := config.Config{App: config.ConfigApp{MaxFetches: 2, MaxHostFetches: 1}}
+	d, err := New(nil, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("should limit concurrent fetches per host", func(t *testing.T) {
+		release := d.acquireFetchSlot("www.example.com")
+		acquired := make(chan struct{})
+		go func() {
+			r := d.acquireFetchSlot("www.example.com")
+			close(acquired)
+			r()
+		}()
+		select {
+		case <-acquired:
+			t.Fatal("acquired second slot for same host")
+		case <-time.After(100 * time.Millisecond):
+		}
+		release()
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("slot was not released")
+		}
+	})
+	t.Run("should allow concurrent fetches for different hosts", func(t *testing.T) {
+		r1 := d.acquireFetchSlot("www.example.com")
+		r2 := d.acquireFetchSlot("www.example.org")
+		r1()
+		r2()
+	})
+}
+
+func TestNewFeedRequest(t *testing.T) {
+	t.Run("should set user agent and custom headers", func(t *testing.T) {
+		d, err := New(nil, config.Config{App: config.ConfigApp{UserAgent: "agent"}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf := config.ConfigFeed{URL: "https://www.example.com/feed", Headers: map[string]string{"X-Custom": "alpha"}}
+		req, err := d.newFeedRequest(cf)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "agent", req.Header.Get("User-Agent"))
+			assert.Equal(t, "alpha", req.Header.Get("X-Custom"))
+		}
+	})
+	t.Run("should use default user agent when not configured", func(t *testing.T) {
+		d, err := New(nil, config.Config{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := d.newFeedRequest(config.ConfigFeed{URL: "https://www.example.com/feed"})
+		if assert.NoError(t, err) {
+			assert.NotEqual(t, "", req.Header.Get("User-Agent"))
+		}
+	})
+	t.Run("should set basic auth", func(t *testing.T) {
+		d, err := New(nil, config.Config{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf := config.ConfigFeed{
+			URL:       "https://www.example.com/feed",
+			BasicAuth: &config.ConfigBasicAuth{Username: "user", Password: "secret"},
+		}
+		req, err := d.newFeedRequest(cf)
+		if assert.NoError(t, err) {
+			username, password, ok := req.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "user", username)
+			assert.Equal(t, "secret", password)
+		}
+	})
+	t.Run("should set bearer token from file", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "token")
+		if err := os.WriteFile(p, []byte("token123\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		d, err := New(nil, config.Config{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf := config.ConfigFeed{URL: "https://www.example.com/feed", BearerTokenFile: p}
+		req, err := d.newFeedRequest(cf)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Bearer token123", req.Header.Get("Authorization"))
+		}
+	})
+}
+
+func TestFetchFeedFromLocalSources(t *testing.T) {
+	p, err := filepath.Abs("testdata/atomfeed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := New(nil, config.Config{App: config.ConfigApp{Timeout: 10}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("should read feed from file URL", func(t *testing.T) {
+		cf := config.ConfigFeed{URL: "file://" + filepath.ToSlash(p)}
+		feed, err := d.fetchFeed(cf)
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, feed.Items)
+		}
+	})
+	t.Run("should return error when file does not exist", func(t *testing.T) {
+		cf := config.ConfigFeed{URL: "file:///does/not/exist.xml"}
+		_, err := d.fetchFeed(cf)
+		assert.Error(t, err)
+	})
+	t.Run("should read feed from command output", func(t *testing.T) {
+		cf := config.ConfigFeed{Command: []string{"cat", p}}
+		feed, err := d.fetchFeed(cf)
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, feed.Items)
+		}
+	})
+	t.Run("should return error when command fails", func(t *testing.T) {
+		cf := config.ConfigFeed{Command: []string{"cat", "/does/not/exist.xml"}}
+		_, err := d.fetchFeed(cf)
+		assert.ErrorContains(t, err, "exist.xml")
+	})
+}
@@ -0,0 +1,156 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+// jsonAPIFeed maps items from a JSON document and returns them as feed.
+// Relative links are resolved against baseURL. Invalid items are skipped.
+func jsonAPIFeed(r io.Reader, baseURL string, cj config.ConfigJSONAPI) (*gofeed.Feed, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("json api: %w", err)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := lookupJSONPath(doc, cj.Items)
+	if !ok {
+		return nil, fmt.Errorf("json api: items not found: %s", cj.Items)
+	}
+	objs, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("json api: items is not an array: %s", cj.Items)
+	}
+	feed := &gofeed.Feed{FeedType: config.FeedTypeJSONAPI}
+	if base.Scheme == "http" || base.Scheme == "https" {
+		feed.Link = baseURL
+	}
+	var errs []error
+	for i, obj := range objs {
+		item, err := jsonAPIItem(obj, base, cj)
+		if err != nil {
+			err = fmt.Errorf("item #%d: %w", i+1, err)
+			slog.Warn("json api: skipped invalid item", "url", baseURL, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	if len(feed.Items) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("json api: %w", errs[0])
+	}
+	return feed, nil
+}
+
+// jsonAPIItem returns an item mapped from a JSON object.
+func jsonAPIItem(obj any, base *url.URL, cj config.ConfigJSONAPI) (*gofeed.Item, error) {
+	item := &gofeed.Item{
+		Title:   jsonPathString(obj, cj.Title),
+		Content: jsonPathString(obj, cj.Content),
+		GUID:    jsonPathString(obj, cj.GUID),
+	}
+	if item.Title == "" {
+		return nil, fmt.Errorf("no title")
+	}
+	if s := jsonPathString(obj, cj.Link); s != "" {
+		u, err := base.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link: %w", err)
+		}
+		item.Link = u.String()
+	}
+	if s := jsonPathString(obj, cj.Image); s != "" {
+		u, err := base.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image: %w", err)
+		}
+		item.Image = &gofeed.Image{URL: u.String()}
+	}
+	if item.GUID == "" {
+		item.GUID = item.Link
+	}
+	if cj.Published != "" {
+		v, _ := lookupJSONPath(obj, cj.Published)
+		switch x := v.(type) {
+		case json.Number:
+			n, err := x.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("published: %w", err)
+			}
+			t := time.Unix(n, 0).UTC()
+			item.Published = x.String()
+			item.PublishedParsed = &t
+		case string:
+			if x != "" {
+				t, err := parseItemDate(x, cj.DateFormat)
+				if err != nil {
+					return nil, fmt.Errorf("published: %w", err)
+				}
+				item.Published = x
+				item.PublishedParsed = &t
+			}
+		}
+	}
+	return item, nil
+}
+
+// lookupJSONPath returns the value at a path in a decoded JSON document
+// and reports wether it was found. An empty path returns the document itself.
+func lookupJSONPath(doc any, path string) (any, bool) {
+	if path == "" {
+		return doc, true
+	}
+	v := doc
+	for _, k := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]any:
+			var ok bool
+			v, ok = x[k]
+			if !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// jsonPathString returns the value at a path as string.
+// Returns an empty string when the path is empty, not found or the value is not a scalar.
+func jsonPathString(doc any, path string) string {
+	if path == "" {
+		return ""
+	}
+	v, _ := lookupJSONPath(doc, path)
+	switch x := v.(type) {
+	case string:
+		return strings.TrimSpace(x)
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return ""
+}
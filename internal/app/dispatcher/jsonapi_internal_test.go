@@ -0,0 +1,105 @@
+package dispatcher
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestJSONAPIFeed(t *testing.T) {
+	t.Run("should map items from array", func(t *testing.T) {
+		f, err := os.Open("testdata/releases.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		cj := config.ConfigJSONAPI{
+			Title:     "name",
+			Link:      "html_url",
+			GUID:      "id",
+			Published: "published_at",
+			Content:   "body",
+			Image:     "author.avatar_url",
+		}
+		feed, err := jsonAPIFeed(f, "https://github.com/", cj)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if assert.Len(t, feed.Items, 2) {
+			x := feed.Items[0]
+			assert.Equal(t, "v2.1.0", x.Title)
+			assert.Equal(t, "https://github.com/example/app/releases/tag/v2.1.0", x.Link)
+			assert.Equal(t, "1001", x.GUID)
+			assert.Equal(t, "Added dark mode.", x.Content)
+			assert.Equal(t, "https://avatars.example.com/u/1", x.Image.URL)
+			assert.Equal(t, time.Date(2024, 8, 20, 10, 0, 0, 0, time.UTC), *x.PublishedParsed)
+			x = feed.Items[1]
+			assert.Equal(t, "https://github.com/example/app/releases/tag/v2.0.0", x.Link)
+			assert.Nil(t, x.Image)
+		}
+	})
+	t.Run("should map items from nested array with unix time", func(t *testing.T) {
+		data := `{"data": {"incidents": [{"title": "Outage", "url": "https://status.example.com/1", "created": 1724320800}]}}`
+		cj := config.ConfigJSONAPI{Items: "data.incidents", Title: "title", Link: "url", Published: "created"}
+		feed, err := jsonAPIFeed(strings.NewReader(data), "", cj)
+		if assert.NoError(t, err) && assert.Len(t, feed.Items, 1) {
+			x := feed.Items[0]
+			assert.Equal(t, "https://status.example.com/1", x.GUID)
+			assert.Equal(t, time.Date(2024, 8, 22, 10, 0, 0, 0, time.UTC), *x.PublishedParsed)
+		}
+	})
+	t.Run("should return error when items is not an array", func(t *testing.T) {
+		cj := config.ConfigJSONAPI{Items: "data", Title: "title"}
+		_, err := jsonAPIFeed(strings.NewReader(`{"data": {}}`), "", cj)
+		assert.Error(t, err)
+	})
+	t.Run("should skip invalid items", func(t *testing.T) {
+		data := `[{"title": "alpha"}, {"name": "bravo"}, {"title": "charlie", "date": "invalid"}, {"title": "delta"}]`
+		cj := config.ConfigJSONAPI{Title: "title", Published: "date"}
+		feed, err := jsonAPIFeed(strings.NewReader(data), "", cj)
+		if assert.NoError(t, err) && assert.Len(t, feed.Items, 2) {
+			assert.Equal(t, "alpha", feed.Items[0].Title)
+			assert.Equal(t, "delta", feed.Items[1].Title)
+		}
+	})
+	t.Run("should return error when all items are invalid", func(t *testing.T) {
+		cj := config.ConfigJSONAPI{Title: "title"}
+		_, err := jsonAPIFeed(strings.NewReader(`[{"name": "alpha"}]`), "", cj)
+		assert.Error(t, err)
+	})
+	t.Run("should return error when document is invalid", func(t *testing.T) {
+		cj := config.ConfigJSONAPI{Title: "title"}
+		_, err := jsonAPIFeed(strings.NewReader(`invalid`), "", cj)
+		assert.Error(t, err)
+	})
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	doc := map[string]any{
+		"alpha": map[string]any{"bravo": []any{"charlie", "delta"}},
+	}
+	cases := []struct {
+		path string
+		want any
+		ok   bool
+	}{
+		{"alpha.bravo.1", "delta", true},
+		{"alpha.bravo.2", nil, false},
+		{"alpha.bravo.x", nil, false},
+		{"alpha.echo", nil, false},
+		{"alpha.bravo.0.foxtrot", nil, false},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			got, ok := lookupJSONPath(doc, tc.path)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
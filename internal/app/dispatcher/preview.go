@@ -0,0 +1,58 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/messenger"
+)
+
+// PreviewItem represents the preview of a feed item as it would be posted to Discord.
+type PreviewItem struct {
+	Title           string
+	Payload         string // Discord message as JSON
+	SkipReason      string
+	ValidationError string
+	ConversionError string
+	Warnings        []string
+}
+
+// PreviewFeed fetches a feed and renders it's first items like they would be posted to Discord,
+// but without posting them. Returns the title of the feed and the previews.
+func (d *Dispatcher) PreviewFeed(feedURL string, limit int) (string, []PreviewItem, error) {
+	if _, err := url.ParseRequestURI(feedURL); err != nil {
+		return "", nil, fmt.Errorf("invalid url: %w", err)
+	}
+	feed, err := d.fetchFeed(config.ConfigFeed{URL: feedURL})
+	if err != nil {
+		return "", nil, fmt.Errorf("parse URL for feed: %w ", err)
+	}
+	items := feed.Items
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	previews := make([]PreviewItem, 0, len(items))
+	for _, item := range items {
+		p := PreviewItem{Title: item.Title, SkipReason: d.itemSkipReason(feed, item)}
+		fi := messenger.NewFeedItem(feed.Title, feed, item, false)
+		m, warnings, err := fi.ToDiscordMessageWithWarnings(d.cfg.App.BrandingDisabled)
+		p.Warnings = warnings
+		if err != nil {
+			p.ConversionError = err.Error()
+			previews = append(previews, p)
+			continue
+		}
+		if err := m.Validate(); err != nil {
+			p.ValidationError = err.Error()
+		}
+		b, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return "", nil, err
+		}
+		p.Payload = string(b)
+		previews = append(previews, p)
+	}
+	return feed.Title, previews, nil
+}
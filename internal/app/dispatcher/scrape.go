@@ -0,0 +1,156 @@
+package dispatcher
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+// itemDateLayouts are the layouts tried for parsing dates, when no format is configured.
+var itemDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02.01.2006",
+}
+
+// scrapeFeed scrapes items from a web page and returns them as feed.
+// Relative links are resolved against baseURL.
+//
+// Items are identified by their link. Items without link and items sharing a link with other items
+// are identified by their title, so that editing their text does not post them again.
+func scrapeFeed(r io.Reader, baseURL string, cs config.ConfigScrape) (*gofeed.Feed, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	feed := &gofeed.Feed{
+		Title:    strings.TrimSpace(doc.Find("title").First().Text()),
+		FeedType: config.FeedTypeScrape,
+	}
+	if base.Scheme == "http" || base.Scheme == "https" {
+		feed.Link = baseURL
+	}
+	var errs []error
+	doc.Find(cs.Item).Each(func(i int, s *goquery.Selection) {
+		item, err := scrapeItem(s, base, cs)
+		if err != nil {
+			err = fmt.Errorf("item #%d: %w", i+1, err)
+			slog.Warn("scrape: skipped invalid item", "url", baseURL, "error", err)
+			errs = append(errs, err)
+			return
+		}
+		feed.Items = append(feed.Items, item)
+	})
+	links := make(map[string]int)
+	for _, item := range feed.Items {
+		links[item.Link]++
+	}
+	for _, item := range feed.Items {
+		switch {
+		case item.Link == "":
+			item.GUID = item.Title
+		case links[item.Link] > 1:
+			item.GUID = item.Link + "#" + item.Title
+		default:
+			item.GUID = item.Link
+		}
+	}
+	if len(feed.Items) == 0 {
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("scrape: %w", errs[0])
+		}
+		return nil, fmt.Errorf("scrape: no items found for selector: %s", cs.Item)
+	}
+	return feed, nil
+}
+
+// scrapeItem returns an item scraped from it's container.
+func scrapeItem(s *goquery.Selection, base *url.URL, cs config.ConfigScrape) (*gofeed.Item, error) {
+	item := &gofeed.Item{
+		Title: strings.TrimSpace(s.Find(cs.Title).First().Text()),
+	}
+	if item.Title == "" {
+		return nil, fmt.Errorf("item has no title")
+	}
+	if cs.Link != "" {
+		if href := scrapeLink(s.Find(cs.Link).First()); href != "" {
+			u, err := base.Parse(href)
+			if err != nil {
+				return nil, fmt.Errorf("item %s: invalid link: %w", item.Title, err)
+			}
+			item.Link = u.String()
+		}
+	}
+	if cs.Content != "" {
+		h, err := s.Find(cs.Content).First().Html()
+		if err != nil {
+			return nil, fmt.Errorf("item %s: content: %w", item.Title, err)
+		}
+		item.Content = strings.TrimSpace(h)
+	}
+	if cs.Date != "" {
+		d := s.Find(cs.Date).First()
+		v, ok := d.Attr("datetime")
+		if !ok {
+			v = d.Text()
+		}
+		v = strings.TrimSpace(v)
+		if v != "" {
+			t, err := parseItemDate(v, cs.DateFormat)
+			if err != nil {
+				return nil, fmt.Errorf("item %s: %w", item.Title, err)
+			}
+			item.Published = v
+			item.PublishedParsed = &t
+		}
+	}
+	return item, nil
+}
+
+// scrapeLink returns the link of an element, which is either the element itself
+// or it's first descendant with a link.
+func scrapeLink(s *goquery.Selection) string {
+	if href, ok := s.Attr("href"); ok {
+		return strings.TrimSpace(href)
+	}
+	href, _ := s.Find("a[href]").First().Attr("href")
+	return strings.TrimSpace(href)
+}
+
+func parseItemDate(v, layout string) (time.Time, error) {
+	if layout != "" {
+		t, err := time.Parse(layout, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parse date: %w", err)
+		}
+		return t.UTC(), nil
+	}
+	for _, l := range itemDateLayouts {
+		t, err := time.Parse(l, v)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("parse date: unknown format: %s", v)
+}
@@ -0,0 +1,122 @@
+package dispatcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestScrapeFeed(t *testing.T) {
+	cs := config.ConfigScrape{
+		Item:    "div.release",
+		Title:   "h2",
+		Link:    "h2",
+		Date:    "time",
+		Content: "div.notes",
+	}
+	t.Run("should scrape items from page", func(t *testing.T) {
+		f, err := os.Open("testdata/changelog.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		feed, err := scrapeFeed(f, "https://www.example.com/changelog", cs)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "Vendor Changelog", feed.Title)
+		assert.Equal(t, "https://www.example.com/changelog", feed.Link)
+		if assert.Len(t, feed.Items, 3) {
+			x := feed.Items[0]
+			assert.Equal(t, "Version 2.1.0", x.Title)
+			assert.Equal(t, "https://www.example.com/changelog/v2.1.0", x.Link)
+			assert.Equal(t, x.Link, x.GUID)
+			assert.Equal(t, "<p>Added <strong>dark mode</strong>.</p>", x.Content)
+			assert.Equal(t, time.Date(2024, 8, 20, 10, 0, 0, 0, time.UTC), *x.PublishedParsed)
+			x = feed.Items[1]
+			assert.Equal(t, "https://www.example.com/changelog/v2.0.0", x.Link)
+			assert.Equal(t, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), *x.PublishedParsed)
+			x = feed.Items[2]
+			assert.Equal(t, "Version 1.0.0", x.Title)
+			assert.Equal(t, "", x.Link)
+			assert.Equal(t, "Version 1.0.0", x.GUID)
+			assert.Nil(t, x.PublishedParsed)
+		}
+	})
+	t.Run("should identify items sharing a link by their title", func(t *testing.T) {
+		html := `<div class="release"><h2><a href="/changelog">Version 2</a></h2></div>
+			<div class="release"><h2><a href="/changelog">Version 1</a></h2></div>`
+		feed, err := scrapeFeed(strings.NewReader(html), "https://www.example.com/", cs)
+		if assert.NoError(t, err) && assert.Len(t, feed.Items, 2) {
+			assert.Equal(t, "https://www.example.com/changelog#Version 2", feed.Items[0].GUID)
+			assert.Equal(t, "https://www.example.com/changelog#Version 1", feed.Items[1].GUID)
+		}
+	})
+	t.Run("should skip invalid items", func(t *testing.T) {
+		html := `<div class="release"><h2>Title</h2></div><div class="release"><p>no title</p></div>`
+		feed, err := scrapeFeed(strings.NewReader(html), "", cs)
+		if assert.NoError(t, err) {
+			assert.Len(t, feed.Items, 1)
+		}
+	})
+	t.Run("should return error when no items found", func(t *testing.T) {
+		_, err := scrapeFeed(strings.NewReader("<html><body></body></html>"), "", cs)
+		assert.Error(t, err)
+	})
+	t.Run("should return error when date can not be parsed", func(t *testing.T) {
+		html := `<div class="release"><h2>Title</h2><time>invalid</time></div>`
+		_, err := scrapeFeed(strings.NewReader(html), "", cs)
+		assert.Error(t, err)
+	})
+	t.Run("should scrape page from file source", func(t *testing.T) {
+		p, err := filepath.Abs("testdata/changelog.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := New(nil, config.Config{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf := config.ConfigFeed{URL: "file://" + filepath.ToSlash(p), Type: config.FeedTypeScrape, Scrape: &cs}
+		feed, err := d.fetchFeed(cf)
+		if assert.NoError(t, err) {
+			assert.Len(t, feed.Items, 3)
+			assert.Equal(t, "", feed.Link)
+		}
+	})
+}
+
+func TestParseItemDate(t *testing.T) {
+	want := time.Date(2024, 8, 22, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in     string
+		layout string
+		ok     bool
+	}{
+		{"2024-08-22", "", true},
+		{"August 22, 2024", "", true},
+		{"22.08.2024", "", true},
+		{"22/08/2024", "02/01/2006", true},
+		{"22/08/2024", "", false},
+		{"invalid", "2006-01-02", false},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			got, err := parseItemDate(tc.in, tc.layout)
+			if tc.ok {
+				if assert.NoError(t, err) {
+					assert.Equal(t, want, got)
+				}
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
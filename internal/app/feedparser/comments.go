@@ -0,0 +1,36 @@
+package feedparser
+
+import (
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/messenger"
+)
+
+// commentsTranslator translates RSS feeds like the default translator,
+// but also keeps the comments URL of items as custom value.
+type commentsTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *commentsTranslator) Translate(feed any) (*gofeed.Feed, error) {
+	f, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	rf, ok := feed.(*rss.Feed)
+	if !ok || len(rf.Items) != len(f.Items) {
+		return f, nil
+	}
+	for i, x := range rf.Items {
+		if x.Comments == "" {
+			continue
+		}
+		item := f.Items[i]
+		if item.Custom == nil {
+			item.Custom = make(map[string]string)
+		}
+		item.Custom[messenger.CustomComments] = x.Comments
+	}
+	return f, nil
+}
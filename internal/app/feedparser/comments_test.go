@@ -0,0 +1,31 @@
+package feedparser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/feedparser"
+	"github.com/ErikKalkoken/feedhook/internal/app/messenger"
+)
+
+func TestCommentsTranslator(t *testing.T) {
+	const rss = `<?xml version="1.0"?>
+<rss version="2.0">
+<channel>
+<title>Feed</title>
+<item><title>First</title><link>https://www.example.com/1</link><comments>https://www.example.com/1#comments</comments></item>
+<item><title>Second</title><link>https://www.example.com/2</link></item>
+</channel>
+</rss>`
+	fp := feedparser.New()
+	t.Run("should keep comments URL of items", func(t *testing.T) {
+		feed, err := fp.Parse(strings.NewReader(rss))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://www.example.com/1#comments", feed.Items[0].Custom[messenger.CustomComments])
+			assert.Equal(t, "", feed.Items[1].Custom[messenger.CustomComments])
+			assert.Equal(t, "Second", feed.Items[1].Title)
+		}
+	})
+}
@@ -0,0 +1,11 @@
+// Package feedparser provides the parser for feeds.
+package feedparser
+
+import "github.com/mmcdole/gofeed"
+
+// New returns a new parser for feeds, which also keeps the comments URL of RSS items.
+func New() *gofeed.Parser {
+	fp := gofeed.NewParser()
+	fp.RSSTranslator = &commentsTranslator{}
+	return fp
+}
@@ -0,0 +1,75 @@
+// Package httpclient provides HTTP clients for outbound connections.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+// New returns a new HTTP client with the given settings and timeout.
+// Supported proxies are HTTP(S) and SOCKS5. Hosts listed in NO_PROXY
+// and localhost are connected directly, as with the standard proxy environment variables.
+func New(c config.ConfigHTTP, timeout time.Duration) (*http.Client, error) {
+	client := &http.Client{Timeout: timeout}
+	if c.IsEmpty() {
+		return client, nil // use default transport
+	}
+	var t *http.Transport
+	if x, ok := http.DefaultTransport.(*http.Transport); ok {
+		t = x.Clone()
+	} else {
+		t = &http.Transport{}
+	}
+	if c.Proxy != "" {
+		if _, err := url.Parse(c.Proxy); err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+		pc := httpproxy.Config{
+			HTTPProxy:  c.Proxy,
+			HTTPSProxy: c.Proxy,
+			NoProxy:    httpproxy.FromEnvironment().NoProxy,
+		}
+		proxy := pc.ProxyFunc()
+		t.Proxy = func(r *http.Request) (*url.URL, error) {
+			return proxy(r.URL)
+		}
+	}
+	if len(c.CAFiles) > 0 || c.ClientCert != "" {
+		tc := &tls.Config{}
+		if len(c.CAFiles) > 0 {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			for _, f := range c.CAFiles {
+				b, err := os.ReadFile(f)
+				if err != nil {
+					return nil, fmt.Errorf("CA file: %w", err)
+				}
+				if !pool.AppendCertsFromPEM(b) {
+					return nil, fmt.Errorf("CA file %s: no valid certificates found", f)
+				}
+			}
+			tc.RootCAs = pool
+		}
+		if c.ClientCert != "" {
+			cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+			if err != nil {
+				return nil, fmt.Errorf("client certificate: %w", err)
+			}
+			tc.Certificates = []tls.Certificate{cert}
+		}
+		t.TLSClientConfig = tc
+	}
+	client.Transport = t
+	return client, nil
+}
@@ -0,0 +1,115 @@
+package httpclient_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/httpclient"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should use default transport when no settings defined", func(t *testing.T) {
+		c, err := httpclient.New(config.ConfigHTTP{}, 5*time.Second)
+		if assert.NoError(t, err) {
+			assert.Nil(t, c.Transport)
+			assert.Equal(t, 5*time.Second, c.Timeout)
+		}
+	})
+	t.Run("can configure proxy", func(t *testing.T) {
+		c, err := httpclient.New(config.ConfigHTTP{Proxy: "socks5://proxy:1080"}, 5*time.Second)
+		if assert.NoError(t, err) {
+			tr := c.Transport.(*http.Transport)
+			req, _ := http.NewRequest("GET", "https://www.example.com", nil)
+			u, err := tr.Proxy(req)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "socks5://proxy:1080", u.String())
+			}
+		}
+	})
+	t.Run("should not proxy localhost and hosts in NO_PROXY", func(t *testing.T) {
+		t.Setenv("NO_PROXY", "internal.example.com, .corp.example.com, 10.0.0.0/8, 192.168.1.5:8080")
+		c, err := httpclient.New(config.ConfigHTTP{Proxy: "http://proxy:8080"}, 5*time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		tr := c.Transport.(*http.Transport)
+		cases := []struct {
+			url       string
+			isProxied bool
+		}{
+			{"http://localhost:5000/translate", false},
+			{"http://127.0.0.1:5000/translate", false},
+			{"http://[::1]:5000/translate", false},
+			{"https://internal.example.com/feed", false},
+			{"https://api.internal.example.com/feed", false},
+			{"https://wiki.corp.example.com/feed", false},
+			{"https://corp.example.com/feed", true},
+			{"http://10.1.2.3/feed", false},
+			{"http://192.168.1.5:8080/feed", false},
+			{"http://192.168.1.5/feed", true},
+			{"https://www.example.com/feed", true},
+		}
+		for i, tc := range cases {
+			t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+				req, _ := http.NewRequest("GET", tc.url, nil)
+				u, err := tr.Proxy(req)
+				if assert.NoError(t, err) {
+					assert.Equal(t, tc.isProxied, u != nil)
+				}
+			})
+		}
+	})
+	t.Run("can add CA file", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "ca.pem")
+		if err := os.WriteFile(p, makeCertificate(t), 0600); err != nil {
+			t.Fatal(err)
+		}
+		c, err := httpclient.New(config.ConfigHTTP{CAFiles: []string{p}}, 5*time.Second)
+		if assert.NoError(t, err) {
+			tr := c.Transport.(*http.Transport)
+			assert.NotNil(t, tr.TLSClientConfig.RootCAs)
+		}
+	})
+	t.Run("should return error when CA file is invalid", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "ca.pem")
+		if err := os.WriteFile(p, []byte("invalid"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := httpclient.New(config.ConfigHTTP{CAFiles: []string{p}}, 5*time.Second)
+		assert.Error(t, err)
+	})
+}
+
+func makeCertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
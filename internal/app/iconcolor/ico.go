@@ -0,0 +1,155 @@
+package iconcolor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+const (
+	icoHeaderSize = 6
+	icoEntrySize  = 16
+	icoMaxSize    = 256 // max width and height of bitmaps in ICO files
+)
+
+var (
+	errInvalidICO     = errors.New("ico: invalid format")
+	errUnsupportedICO = errors.New("ico: unsupported format")
+	pngSignature      = []byte("\x89PNG\r\n\x1a\n")
+)
+
+func init() {
+	image.RegisterFormat("ico", "\x00\x00\x01\x00", decodeICO, decodeICOConfig)
+}
+
+// decodeICO decodes the largest image of an ICO file.
+// Supported are images stored as PNG and as uncompressed 24 or 32 bit bitmaps.
+func decodeICO(r io.Reader) (image.Image, error) {
+	data, err := readICO(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, pngSignature) {
+		return png.Decode(bytes.NewReader(data))
+	}
+	return decodeBitmap(data)
+}
+
+func decodeICOConfig(r io.Reader) (image.Config, error) {
+	data, err := readICO(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if bytes.HasPrefix(data, pngSignature) {
+		return png.DecodeConfig(bytes.NewReader(data))
+	}
+	h, err := parseBitmapHeader(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: h.width, Height: h.height}, nil
+}
+
+// readICO returns the data of the largest image in an ICO file.
+func readICO(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < icoHeaderSize || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return nil, errInvalidICO
+	}
+	n := int(binary.LittleEndian.Uint16(data[4:]))
+	if len(data) < icoHeaderSize+n*icoEntrySize {
+		return nil, errInvalidICO
+	}
+	var best []byte
+	var bestPixels int
+	for i := range n {
+		e := data[icoHeaderSize+i*icoEntrySize:]
+		w, h := int(e[0]), int(e[1])
+		if w == 0 {
+			w = icoMaxSize
+		}
+		if h == 0 {
+			h = icoMaxSize
+		}
+		size := uint64(binary.LittleEndian.Uint32(e[8:]))
+		offset := uint64(binary.LittleEndian.Uint32(e[12:]))
+		if size == 0 || offset+size > uint64(len(data)) {
+			continue
+		}
+		if w*h > bestPixels {
+			best = data[offset : offset+size]
+			bestPixels = w * h
+		}
+	}
+	if best == nil {
+		return nil, errInvalidICO
+	}
+	return best, nil
+}
+
+type bitmapHeader struct {
+	size          int
+	width, height int
+	bitCount      int
+}
+
+// parseBitmapHeader parses the header of a bitmap in an ICO file.
+// The height of these bitmaps includes the transparency mask and is therefore doubled.
+func parseBitmapHeader(data []byte) (bitmapHeader, error) {
+	if len(data) < 40 {
+		return bitmapHeader{}, errInvalidICO
+	}
+	h := bitmapHeader{
+		size:     int(binary.LittleEndian.Uint32(data[0:])),
+		width:    int(int32(binary.LittleEndian.Uint32(data[4:]))),
+		height:   int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2,
+		bitCount: int(binary.LittleEndian.Uint16(data[14:])),
+	}
+	if h.size < 40 || h.size > len(data) || h.width <= 0 || h.height <= 0 || h.width > icoMaxSize || h.height > icoMaxSize {
+		return bitmapHeader{}, errInvalidICO
+	}
+	if compression := binary.LittleEndian.Uint32(data[16:]); compression != 0 || (h.bitCount != 24 && h.bitCount != 32) {
+		return bitmapHeader{}, errUnsupportedICO
+	}
+	return h, nil
+}
+
+// decodeBitmap decodes a bitmap of an ICO file.
+// Pixels of 24 bit bitmaps are transparent when set in the transparency mask.
+func decodeBitmap(data []byte) (image.Image, error) {
+	h, err := parseBitmapHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	bpp := h.bitCount / 8
+	stride := (h.width*h.bitCount + 31) / 32 * 4
+	maskStride := (h.width + 31) / 32 * 4
+	pixels := data[h.size:]
+	if len(pixels) < h.height*stride {
+		return nil, errInvalidICO
+	}
+	mask := pixels[h.height*stride:]
+	hasMask := h.bitCount == 24 && len(mask) >= h.height*maskStride
+	img := image.NewNRGBA(image.Rect(0, 0, h.width, h.height))
+	for y := range h.height {
+		row := pixels[(h.height-1-y)*stride:] // rows are stored bottom-up
+		for x := range h.width {
+			p := row[x*bpp:]
+			c := color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+			if bpp == 4 {
+				c.A = p[3]
+			} else if hasMask && mask[(h.height-1-y)*maskStride+x/8]&(0x80>>(x%8)) != 0 {
+				c.A = 0
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,86 @@
+// Package iconcolor derives colors from the icons of feeds.
+package iconcolor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/cachedfetch"
+)
+
+const (
+	maxImageSize   = 1024 * 1024
+	maxImagePixels = 2048 * 2048 // larger images are rejected before decoding
+	maxSamples     = 64          // max number of sampled pixels per row and column
+)
+
+// Fetcher fetches icons and returns their average colors.
+// Results are cached, including icons without color.
+type Fetcher struct {
+	f *cachedfetch.Fetcher[int]
+}
+
+// New returns a new Fetcher for icon colors. Requests are aborted after timeout.
+func New(client *http.Client, clock cachedfetch.Clock, timeout time.Duration, userAgent string) *Fetcher {
+	return &Fetcher{f: cachedfetch.New(client, clock, timeout, userAgent, parseColor)}
+}
+
+// Color returns the average color of the visible pixels of an icon.
+// Returns 0 when the icon has no visible pixels.
+// Icons must be PNG, JPEG, GIF or ICO images.
+func (f *Fetcher) Color(iconURL string) (int, error) {
+	return f.f.Get(iconURL)
+}
+
+func parseColor(resp *http.Response) (int, error) {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize))
+	if err != nil {
+		return 0, fmt.Errorf("read icon: %w", err)
+	}
+	// the size of an image is checked first, because small files can declare huge images
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("decode icon: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return 0, fmt.Errorf("decode icon: image too large: %dx%d", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("decode icon: %w", err)
+	}
+	return averageColor(img), nil
+}
+
+// averageColor returns the average color of the visible pixels of an image.
+// Large images are sampled.
+func averageColor(img image.Image) int {
+	b := img.Bounds()
+	stepX := max(b.Dx()/maxSamples, 1)
+	stepY := max(b.Dy()/maxSamples, 1)
+	var r, g, bl, n uint64
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			cr, cg, cb, ca := img.At(x, y).RGBA()
+			if ca < 0x8000 {
+				continue // mostly transparent
+			}
+			// un-premultiply and reduce to 8 bit
+			r += uint64(cr) * 0xffff / uint64(ca) >> 8
+			g += uint64(cg) * 0xffff / uint64(ca) >> 8
+			bl += uint64(cb) * 0xffff / uint64(ca) >> 8
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return int(r/n)<<16 | int(g/n)<<8 | int(bl/n)
+}
@@ -0,0 +1,169 @@
+package iconcolor_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/iconcolor"
+)
+
+type fakeTime struct {
+	now time.Time
+}
+
+func (rt fakeTime) Now() time.Time {
+	return rt.now
+}
+
+func makePNG(t *testing.T, c color.Color) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := range 4 {
+		for x := range 4 {
+			if x < 2 {
+				img.Set(x, y, c)
+			}
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// makeICO returns an ICO file with one image.
+func makeICO(width, height int, data []byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, []uint16{0, 1, 1})
+	b.Write([]byte{byte(width), byte(height), 0, 0})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 32})
+	binary.Write(&b, binary.LittleEndian, []uint32{uint32(len(data)), 22})
+	b.Write(data)
+	return b.Bytes()
+}
+
+// makeBitmap returns a 32 bit bitmap as stored in ICO files with all pixels in one color.
+func makeBitmap(width, height int, c color.NRGBA) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, []uint32{40, uint32(width), uint32(height * 2)})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 32})
+	binary.Write(&b, binary.LittleEndian, make([]uint32, 6))
+	for range width * height {
+		b.Write([]byte{c.B, c.G, c.R, c.A})
+	}
+	b.Write(make([]byte, height*((width+31)/32*4))) // transparency mask
+	return b.Bytes()
+}
+
+func TestFetcher(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	clock := fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)}
+	t.Run("should return average color of visible pixels", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/icon.png",
+			httpmock.NewBytesResponder(200, makePNG(t, color.NRGBA{R: 0x1e, G: 0x90, B: 0xff, A: 0xff})),
+		)
+		f := iconcolor.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		got, err := f.Color("https://www.example.com/icon.png")
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0x1e90ff, got)
+		}
+	})
+	t.Run("should return 0 when icon is transparent", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/icon.png",
+			httpmock.NewBytesResponder(200, makePNG(t, color.NRGBA{})),
+		)
+		f := iconcolor.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		got, err := f.Color("https://www.example.com/icon.png")
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, got)
+		}
+	})
+	t.Run("should return cached color", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/icon.png",
+			httpmock.NewBytesResponder(200, makePNG(t, color.NRGBA{R: 0xff, A: 0xff})),
+		)
+		f := iconcolor.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		_, err := f.Color("https://www.example.com/icon.png")
+		if assert.NoError(t, err) {
+			got, err := f.Color("https://www.example.com/icon.png")
+			if assert.NoError(t, err) {
+				assert.Equal(t, 0xff0000, got)
+				assert.Equal(t, 1, httpmock.GetTotalCallCount())
+			}
+		}
+	})
+	t.Run("should return average color of ICO with PNG image", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/favicon.ico",
+			httpmock.NewBytesResponder(200, makeICO(4, 4, makePNG(t, color.NRGBA{R: 0x1e, G: 0x90, B: 0xff, A: 0xff}))),
+		)
+		f := iconcolor.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		got, err := f.Color("https://www.example.com/favicon.ico")
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0x1e90ff, got)
+		}
+	})
+	t.Run("should return average color of ICO with bitmap image", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/favicon.ico",
+			httpmock.NewBytesResponder(200, makeICO(16, 16, makeBitmap(16, 16, color.NRGBA{R: 0x1e, G: 0x90, B: 0xff, A: 0xff}))),
+		)
+		f := iconcolor.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		got, err := f.Color("https://www.example.com/favicon.ico")
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0x1e90ff, got)
+		}
+	})
+	t.Run("should return error when icon is too large", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 3000, 3000))); err != nil {
+			t.Fatal(err)
+		}
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://www.example.com/icon.png", httpmock.NewBytesResponder(200, b.Bytes()))
+		f := iconcolor.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		_, err := f.Color("https://www.example.com/icon.png")
+		assert.Error(t, err)
+	})
+	t.Run("should return error when icon is not an image", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/favicon.ico",
+			httpmock.NewStringResponder(200, "not an image"),
+		)
+		f := iconcolor.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		_, err := f.Color("https://www.example.com/favicon.ico")
+		assert.Error(t, err)
+	})
+	t.Run("should return error when request fails", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://www.example.com/icon.png", httpmock.NewStringResponder(404, ""))
+		f := iconcolor.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		_, err := f.Color("https://www.example.com/icon.png")
+		assert.Error(t, err)
+	})
+}
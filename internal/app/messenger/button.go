@@ -0,0 +1,45 @@
+package messenger
+
+import (
+	"github.com/ErikKalkoken/go-dhook"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+// CustomComments is the key for the comments URL in the custom values of an item.
+const CustomComments = "comments"
+
+// Discord component types and styles
+const (
+	componentTypeActionRow = 1
+	componentTypeButton    = 2
+	buttonStyleLink        = 5
+)
+
+// actionRows returns the action rows with link buttons for an item.
+// Buttons without valid URLs are omitted.
+func (fi FeedItem) actionRows(buttons []string) []dhook.ActionRow {
+	components := make([]dhook.Button, 0, len(buttons))
+	for _, b := range buttons {
+		var label, u string
+		switch b {
+		case config.ButtonArticle:
+			label, u = "Open article", fi.ItemURL
+		case config.ButtonComments:
+			label, u = "Comments", fi.CommentsURL
+		}
+		if u == "" || !isValidPublicURL(u) {
+			continue
+		}
+		components = append(components, dhook.Button{
+			Type:  componentTypeButton,
+			Style: buttonStyleLink,
+			Label: label,
+			URL:   u,
+		})
+	}
+	if len(components) == 0 {
+		return nil
+	}
+	return []dhook.ActionRow{{Type: componentTypeActionRow, Components: components}}
+}
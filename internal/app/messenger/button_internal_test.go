@@ -0,0 +1,45 @@
+package messenger
+
+import (
+	"testing"
+
+	"github.com/ErikKalkoken/go-dhook"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestActionRows(t *testing.T) {
+	t.Run("should return link buttons for article and comments", func(t *testing.T) {
+		fi := FeedItem{ItemURL: "https://www.example.com/item", CommentsURL: "https://www.example.com/item#comments"}
+		got := fi.actionRows([]string{config.ButtonArticle, config.ButtonComments})
+		want := []dhook.ActionRow{{
+			Type: componentTypeActionRow,
+			Components: []dhook.Button{
+				{Type: componentTypeButton, Style: buttonStyleLink, Label: "Open article", URL: "https://www.example.com/item"},
+				{Type: componentTypeButton, Style: buttonStyleLink, Label: "Comments", URL: "https://www.example.com/item#comments"},
+			},
+		}}
+		assert.Equal(t, want, got)
+	})
+	t.Run("should omit buttons without valid URL", func(t *testing.T) {
+		fi := FeedItem{ItemURL: "https://www.example.com/item", CommentsURL: "invalid"}
+		got := fi.actionRows([]string{config.ButtonArticle, config.ButtonComments})
+		if assert.Len(t, got, 1) {
+			assert.Len(t, got[0].Components, 1)
+			assert.Equal(t, "Open article", got[0].Components[0].Label)
+		}
+	})
+	t.Run("should return no action rows when no button has a URL", func(t *testing.T) {
+		fi := FeedItem{}
+		got := fi.actionRows([]string{config.ButtonArticle, config.ButtonComments})
+		assert.Nil(t, got)
+	})
+	t.Run("should add buttons to last message", func(t *testing.T) {
+		fi := FeedItem{Title: "title", ItemURL: "https://www.example.com/item"}
+		dms, _, err := fi.ToDiscordMessages(RenderOptions{Buttons: []string{config.ButtonArticle}})
+		if assert.NoError(t, err) {
+			assert.Len(t, dms[len(dms)-1].Components, 1)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package messenger
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"math"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+// embedColor returns the color of embeds for an item. Returns 0 for no color.
+func embedColor(mode string, fi FeedItem) int {
+	switch mode {
+	case "":
+		return 0
+	case config.ColorHash:
+		return hashColor(fi.FeedName)
+	case config.ColorIcon:
+		if fi.IconColor != 0 {
+			return fi.IconColor
+		}
+		return hashColor(fi.FeedName)
+	}
+	c, err := config.ParseColor(mode)
+	if err != nil {
+		slog.Warn("Invalid embed color", "color", mode)
+		return 0
+	}
+	return c
+}
+
+// hashColor returns a color derived from a text.
+// The same text always returns the same color.
+func hashColor(s string) int {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	v := h.Sum32()
+	// hue from the hash with fixed saturation and lightness gives colors, which are readable on Discord
+	return hslToRGB(float64(v%360), 0.65, 0.55)
+}
+
+// hslToRGB converts a color from HSL to RGB.
+func hslToRGB(h, s, l float64) int {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return int((r+m)*255+0.5)<<16 | int((g+m)*255+0.5)<<8 | int((b+m)*255+0.5)
+}
@@ -0,0 +1,55 @@
+package messenger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbedColor(t *testing.T) {
+	cases := []struct {
+		mode string
+		fi   FeedItem
+		want int
+	}{
+		{"", FeedItem{FeedName: "alpha"}, 0},
+		{"#1e90ff", FeedItem{FeedName: "alpha"}, 0x1e90ff},
+		{"hash", FeedItem{FeedName: "alpha"}, hashColor("alpha")},
+		{"icon", FeedItem{FeedName: "alpha", IconColor: 0x123456}, 0x123456},
+		{"icon", FeedItem{FeedName: "alpha"}, hashColor("alpha")},
+		{"invalid", FeedItem{FeedName: "alpha"}, 0},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, embedColor(tc.mode, tc.fi))
+		})
+	}
+}
+
+func TestHashColor(t *testing.T) {
+	t.Run("should return same color for same text", func(t *testing.T) {
+		assert.Equal(t, hashColor("alpha"), hashColor("alpha"))
+	})
+	t.Run("should return different colors for different texts", func(t *testing.T) {
+		assert.NotEqual(t, hashColor("alpha"), hashColor("bravo"))
+	})
+}
+
+func TestHSLToRGB(t *testing.T) {
+	cases := []struct {
+		h, s, l float64
+		want    int
+	}{
+		{0, 1, 0.5, 0xff0000},
+		{120, 1, 0.5, 0x00ff00},
+		{240, 1, 0.5, 0x0000ff},
+		{0, 0, 1, 0xffffff},
+		{0, 0, 0, 0x000000},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, hslToRGB(tc.h, tc.s, tc.l))
+		})
+	}
+}
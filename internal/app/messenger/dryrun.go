@@ -0,0 +1,50 @@
+package messenger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/go-dhook"
+)
+
+var dryRunMu sync.Mutex
+
+type dryRunRecord struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Webhook   string        `json:"webhook"`
+	Message   dhook.Message `json:"message"`
+}
+
+// ExecuteOrRecord sends a message to a webhook.
+// In dry-run mode the message is recorded instead,
+// either as JSON line in the configured dry-run file or in the log.
+func ExecuteOrRecord(cfg config.Config, wh *dhook.Webhook, webhookName string, dm dhook.Message) error {
+	if !cfg.App.DryRun {
+		_, err := wh.Execute(dm, nil)
+		return err
+	}
+	r := dryRunRecord{Timestamp: time.Now().UTC(), Webhook: webhookName, Message: dm}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if cfg.App.DryRunFile == "" {
+		slog.Info("Dry-run: message not sent", "webhook", webhookName, "payload", string(b))
+		return nil
+	}
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	f, err := os.OpenFile(cfg.App.DryRunFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
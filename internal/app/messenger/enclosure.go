@@ -0,0 +1,105 @@
+package messenger
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	embedMaxFields           = 25
+	embedMaxFieldValueLength = 1024
+)
+
+// Enclosure represents a file attached to a feed item, e.g. the audio file of a podcast episode.
+type Enclosure struct {
+	Length int64 // size in bytes. Zero when unknown.
+	Type   string
+	URL    string
+}
+
+func newEnclosures(item *gofeed.Item) []Enclosure {
+	ee := make([]Enclosure, 0)
+	for _, x := range item.Enclosures {
+		if x == nil || x.URL == "" {
+			continue
+		}
+		length, _ := strconv.ParseInt(x.Length, 10, 64)
+		ee = append(ee, Enclosure{Length: max(length, 0), Type: x.Type, URL: x.URL})
+	}
+	return ee
+}
+
+// Kind returns the kind of an enclosure, e.g. "audio", based on it's MIME type.
+func (e Enclosure) Kind() string {
+	k, _, _ := strings.Cut(e.Type, "/")
+	switch k {
+	case "audio", "image", "video":
+		return k
+	}
+	return "file"
+}
+
+// FieldName returns the name for an embed field showing an enclosure.
+func (e Enclosure) FieldName() string {
+	switch e.Kind() {
+	case "audio":
+		return "Audio"
+	case "image":
+		return "Image"
+	case "video":
+		return "Video"
+	}
+	return "File"
+}
+
+// Markdown returns a markdown link to an enclosure with it's size.
+func (e Enclosure) Markdown() string {
+	name := "Download"
+	if u, err := url.Parse(e.URL); err == nil {
+		if b := path.Base(u.Path); b != "" && b != "/" && b != "." {
+			name = b
+		}
+	}
+	s := fmt.Sprintf("[%s](%s)", name, e.URL)
+	if e.Length > 0 {
+		s += fmt.Sprintf(" (%s)", humanize.Bytes(uint64(e.Length)))
+	}
+	return s
+}
+
+// formatDuration returns an iTunes duration in a uniform format, e.g. "1:02:03".
+// A duration can be given in seconds or as "mm:ss" or "hh:mm:ss".
+// Returns the duration unchanged when it has an unknown format.
+func formatDuration(s string) string {
+	s = strings.TrimSpace(s)
+	var seconds int
+	for _, p := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return s
+		}
+		seconds = seconds*60 + n
+	}
+	h, m, sec := seconds/3600, seconds%3600/60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
+
+// formatEpisode returns the episode number with it's season, e.g. "S2 E12".
+func formatEpisode(season, episode string) string {
+	if episode == "" {
+		return ""
+	}
+	if season == "" {
+		return episode
+	}
+	return fmt.Sprintf("S%s E%s", season, episode)
+}
@@ -0,0 +1,40 @@
+package messenger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDuration(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"3723", "1:02:03"},
+		{"62:03", "1:02:03"},
+		{"01:02:03", "1:02:03"},
+		{"45", "0:45"},
+		{"5:07", "5:07"},
+		{"invalid", "invalid"},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, formatDuration(tc.in))
+		})
+	}
+}
+
+func TestEnclosure(t *testing.T) {
+	t.Run("should show link with file name and size", func(t *testing.T) {
+		e := Enclosure{URL: "https://www.example.com/media/episode12.mp3", Type: "audio/mpeg", Length: 12_300_000}
+		assert.Equal(t, "Audio", e.FieldName())
+		assert.Equal(t, "[episode12.mp3](https://www.example.com/media/episode12.mp3) (12 MB)", e.Markdown())
+	})
+	t.Run("should show link without size when unknown", func(t *testing.T) {
+		e := Enclosure{URL: "https://www.example.com/", Type: "application/pdf"}
+		assert.Equal(t, "File", e.FieldName())
+		assert.Equal(t, "[Download](https://www.example.com/)", e.Markdown())
+	})
+}
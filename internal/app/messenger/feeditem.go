@@ -5,13 +5,13 @@ import (
 	"html"
 	"log/slog"
 	"net/url"
-	"strings"
+	"slices"
 	"time"
 
 	"github.com/ErikKalkoken/go-dhook"
-	md "github.com/JohannesKaufmann/html-to-markdown"
-	"github.com/PuerkitoBio/goquery"
 	"github.com/mmcdole/gofeed"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
 )
 
 const (
@@ -22,51 +22,18 @@ const (
 	username                  = "Feedhook"
 )
 
-var converter = md.NewConverter("", true, nil)
-
-func init() {
-	removeImgTags := md.Rule{
-		Filter: []string{"img"},
-		Replacement: func(_ string, _ *goquery.Selection, _ *md.Options) *string {
-			return md.String("")
-		},
-	}
-	removeFigureTags := md.Rule{
-		Filter: []string{"figure"},
-		Replacement: func(_ string, _ *goquery.Selection, _ *md.Options) *string {
-			return md.String("")
-		},
-	}
-	sanitizeMailToLinks := md.Rule{
-		Filter: []string{"a"},
-		Replacement: func(content string, selec *goquery.Selection, options *md.Options) *string {
-			href := selec.AttrOr("href", "#")
-			if strings.HasPrefix(href, "mailto:") {
-				return md.String(content)
-			}
-			return nil
-		},
-	}
-	sanitizeInvalidLinks := md.Rule{
-		Filter: []string{"a"},
-		Replacement: func(content string, selec *goquery.Selection, options *md.Options) *string {
-			_, err := url.ParseRequestURI(content)
-			if err == nil {
-				href := selec.AttrOr("href", "#")
-				return md.String("[Link](" + href + ")")
-			}
-			return nil
-		},
-	}
-	converter.AddRules(removeImgTags, removeFigureTags, sanitizeMailToLinks, sanitizeInvalidLinks)
-}
-
 // FeedItem represents a feed item to be posted to a webhook
 type FeedItem struct {
+	ArtworkURL  string // episode artwork of a podcast
+	CommentsURL string
 	Description string
+	Duration    string // duration of a podcast episode
+	Enclosures  []Enclosure
+	Episode     string // number of a podcast episode, optionally with season
 	FeedName    string
 	FeedTitle   string
 	FeedURL     string
+	IconColor   int // average color of the feed's icon. 0 when unknown.
 	IconURL     string
 	ImageURL    string
 	IsUpdated   bool
@@ -84,6 +51,8 @@ func NewFeedItem(feedName string, feed *gofeed.Feed, item *gofeed.Item, isUpdate
 	}
 	fi := FeedItem{
 		Description: description,
+		CommentsURL: item.Custom[CustomComments],
+		Enclosures:  newEnclosures(item),
 		FeedName:    feedName,
 		FeedTitle:   feed.Title,
 		FeedURL:     feed.Link,
@@ -97,34 +66,130 @@ func NewFeedItem(feedName string, feed *gofeed.Feed, item *gofeed.Item, isUpdate
 	if feed.Image != nil {
 		fi.IconURL = feed.Image.URL
 	}
-	if item.Image != nil {
-		fi.ImageURL = item.Image.URL
+	fi.ImageURL = ItemImageURL(item)
+	if x := item.ITunesExt; x != nil {
+		fi.ArtworkURL = x.Image
+		fi.Episode = formatEpisode(x.Season, x.Episode)
+		if x.Duration != "" {
+			fi.Duration = formatDuration(x.Duration)
+		}
 	}
 	return fi
 }
 
 // ToDiscordMessage generates a DiscordMessage from a FeedItem.
 func (fi FeedItem) ToDiscordMessage(brandingDisabled bool) (dhook.Message, error) {
-	var dm dhook.Message
-	description, err := converter.ConvertString(fi.Description)
+	dm, warnings, err := fi.ToDiscordMessageWithWarnings(brandingDisabled)
+	for _, w := range warnings {
+		slog.Warn(w, "title", fi.Title)
+	}
+	return dm, err
+}
+
+// ToDiscordMessageWithWarnings generates a DiscordMessage from a FeedItem
+// and also returns warnings about any issues with the conversion, e.g. truncated texts.
+func (fi FeedItem) ToDiscordMessageWithWarnings(brandingDisabled bool) (dhook.Message, []string, error) {
+	dms, warnings, err := fi.ToDiscordMessages(RenderOptions{BrandingDisabled: brandingDisabled})
 	if err != nil {
-		return dm, fmt.Errorf("convert description to markdown: %w", err)
+		return dhook.Message{}, warnings, err
 	}
-	desc, truncated := truncateString(description, embedDescriptionMaxLength)
-	if truncated {
-		slog.Warn("description was truncated", "title", fi.Title)
+	return dms[0], warnings, nil
+}
+
+// RenderOptions defines how a FeedItem is rendered into Discord messages.
+type RenderOptions struct {
+	BrandingDisabled bool
+	LongContent      string // how long descriptions are handled. Defaults to truncating.
+	MaxMessages      int    // max number of messages for split descriptions
+	Mentions         []config.ConfigMention
+	Appearance       config.ConfigAppearance
+	Buttons          []string // link buttons shown below a post
+	Markdown         config.ConfigMarkdown
+	Forum            bool     // creates a forum post with a single message
+	ForumTags        []string // IDs of tags applied to forum posts
+	ThreadID         string   // ID of the thread to post into
+}
+
+// NewRenderOptions returns the render options for a feed posted to a webhook.
+func NewRenderOptions(cfg config.Config, feedName, webhookName string) RenderOptions {
+	opts := RenderOptions{BrandingDisabled: cfg.App.BrandingDisabled}
+	i := slices.IndexFunc(cfg.Feeds, func(cf config.ConfigFeed) bool {
+		return cf.Name == feedName
+	})
+	if i != -1 {
+		cf := cfg.Feeds[i]
+		opts.LongContent = cf.LongContent
+		opts.MaxMessages = cf.MaxMessages
+		if cf.Mention != nil {
+			opts.Mentions = append(opts.Mentions, *cf.Mention)
+		}
+		opts.ThreadID = cf.ThreadID
+		opts.ForumTags = cf.ForumTags
+		opts.Appearance = cf.ConfigAppearance
+		opts.Buttons = cf.Buttons
+		if cf.Markdown != nil {
+			opts.Markdown = *cf.Markdown
+		}
+	}
+	i = slices.IndexFunc(cfg.Webhooks, func(wh config.ConfigWebhook) bool {
+		return wh.Name == webhookName
+	})
+	if i != -1 {
+		wh := cfg.Webhooks[i]
+		if wh.Mention != nil {
+			opts.Mentions = append(opts.Mentions, *wh.Mention)
+		}
+		if opts.ThreadID == "" {
+			opts.ThreadID = wh.ThreadID
+		}
+		if len(opts.ForumTags) == 0 {
+			opts.ForumTags = wh.ForumTags
+		}
+		opts.Forum = wh.Forum && opts.ThreadID == ""
+		opts.Appearance = mergeAppearance(wh.ConfigAppearance, opts.Appearance)
+	}
+	if !opts.Forum {
+		opts.ForumTags = nil
+	}
+	return opts
+}
+
+// mergeAppearance returns the appearance of a webhook with the values defined in override.
+func mergeAppearance(base, override config.ConfigAppearance) config.ConfigAppearance {
+	if override.AvatarURL != "" || override.AvatarFeedIcon {
+		base.AvatarURL = override.AvatarURL
+		base.AvatarFeedIcon = override.AvatarFeedIcon
+	}
+	if override.Color != "" {
+		base.Color = override.Color
+	}
+	if override.Username != "" {
+		base.Username = override.Username
+	}
+	return base
+}
+
+// ToDiscordMessages generates one or more DiscordMessages from a FeedItem.
+// Long descriptions can be split over several embeds and messages.
+// Also returns warnings about any issues with the conversion, e.g. truncated texts.
+func (fi FeedItem) ToDiscordMessages(opts RenderOptions) ([]dhook.Message, []string, error) {
+	var dm dhook.Message
+	warnings := make([]string, 0)
+	description, err := convertDescription(fi.Description, opts.Markdown)
+	if err != nil {
+		return nil, warnings, fmt.Errorf("convert description to markdown: %w", err)
 	}
-	t := html.UnescapeString(fi.Title)
+	description = sanitizeMentions(description)
+	t := sanitizeMentions(html.UnescapeString(fi.Title))
 	if fi.IsUpdated {
 		t = fmt.Sprintf("UPDATED: %s", t)
 	}
 	title, truncated := truncateString(t, embedMaxFieldLength)
 	if truncated {
-		slog.Warn("title was truncated", "title", fi.Title)
+		warnings = append(warnings, "title was truncated")
 	}
 	em := dhook.Embed{
-		Description: desc,
-		Title:       title,
+		Title: title,
 	}
 	if fi.ItemURL != "" && isValidPublicURL(fi.ItemURL) {
 		em.URL = fi.ItemURL
@@ -132,10 +197,10 @@ func (fi FeedItem) ToDiscordMessage(brandingDisabled bool) (dhook.Message, error
 	if !fi.Published.IsZero() {
 		em.Timestamp = fi.Published
 	}
-	ft := html.UnescapeString(fi.FeedTitle)
+	ft := sanitizeMentions(html.UnescapeString(fi.FeedTitle))
 	em.Author.Name, truncated = truncateString(ft, embedMaxFieldLength)
 	if truncated {
-		slog.Warn("author name was truncated", "FeedTitle", fi.FeedTitle)
+		warnings = append(warnings, "author name was truncated")
 	}
 	if fi.FeedURL != "" && isValidPublicURL(fi.FeedURL) {
 		em.Author.URL = fi.FeedURL
@@ -146,13 +211,88 @@ func (fi FeedItem) ToDiscordMessage(brandingDisabled bool) (dhook.Message, error
 	if fi.ImageURL != "" && isValidPublicURL(fi.ImageURL) {
 		em.Image.URL = fi.ImageURL
 	}
-	if !brandingDisabled {
+	if fi.ArtworkURL != "" && isValidPublicURL(fi.ArtworkURL) {
+		em.Thumbnail.URL = fi.ArtworkURL
+	}
+	if fi.Episode != "" {
+		v, truncated := truncateString(fi.Episode, embedMaxFieldValueLength)
+		if truncated {
+			warnings = append(warnings, "episode was truncated")
+		}
+		em.Fields = append(em.Fields, dhook.Field{Name: "Episode", Value: v, Inline: true})
+	}
+	if fi.Duration != "" {
+		v, truncated := truncateString(fi.Duration, embedMaxFieldValueLength)
+		if truncated {
+			warnings = append(warnings, "duration was truncated")
+		}
+		em.Fields = append(em.Fields, dhook.Field{Name: "Duration", Value: v, Inline: true})
+	}
+	for _, e := range fi.Enclosures {
+		if !isValidPublicURL(e.URL) {
+			continue
+		}
+		if e.Kind() == "image" {
+			if em.Image.URL == "" {
+				em.Image.URL = e.URL
+			}
+			continue
+		}
+		if len(em.Fields) == embedMaxFields {
+			warnings = append(warnings, "enclosures were omitted")
+			break
+		}
+		v := e.Markdown()
+		if len([]rune(v)) > embedMaxFieldValueLength {
+			warnings = append(warnings, "enclosure was omitted because it's URL is too long")
+			continue
+		}
+		em.Fields = append(em.Fields, dhook.Field{Name: e.FieldName(), Value: v})
+	}
+	if !opts.BrandingDisabled {
 		dm.Username = username
 		dm.AvatarURL = avatarURL
 	}
+	if x := opts.Appearance.Username; x != "" {
+		dm.Username = x
+	}
+	if x := opts.Appearance.AvatarURL; x != "" {
+		dm.AvatarURL = x
+	} else if opts.Appearance.AvatarFeedIcon && fi.IconURL != "" && isValidPublicURL(fi.IconURL) {
+		dm.AvatarURL = fi.IconURL
+	}
+	em.Color = embedColor(opts.Appearance.Color, fi)
 	em.Footer = dhook.Footer{Text: fi.FeedName}
-	dm.Embeds = []dhook.Embed{em}
-	return dm, nil
+	content, allowedMentions := newMentions(opts.Mentions, t+"\n"+description)
+	dm.AllowedMentions = allowedMentions
+	if opts.Forum {
+		opts.MaxMessages = 1 // follow-up messages can not be posted into a new forum post
+	}
+	layout, w := layoutDescription(description, embedLength(em), em.URL, opts)
+	warnings = append(warnings, w...)
+	dms := make([]dhook.Message, 0, len(layout))
+	for i, descriptions := range layout {
+		m := dm
+		m.Embeds = make([]dhook.Embed, 0, len(descriptions))
+		for j, d := range descriptions {
+			var x dhook.Embed
+			if i == 0 && j == 0 {
+				x = em
+			}
+			x.Description = d
+			m.Embeds = append(m.Embeds, x)
+		}
+		dms = append(dms, m)
+	}
+	dms[0].Content = content
+	if rows := fi.actionRows(opts.Buttons); len(rows) > 0 {
+		dms[len(dms)-1].Components = rows
+	}
+	if opts.Forum {
+		dms[0].ThreadName = fi.threadName()
+		dms[0].AppliedTags = opts.ForumTags
+	}
+	return dms, warnings, nil
 }
 
 // truncateString truncates a given string if it longer then a limit
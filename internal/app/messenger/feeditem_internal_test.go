@@ -2,10 +2,14 @@ package messenger
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/mmcdole/gofeed"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
 )
 
 func TestFeedItem(t *testing.T) {
@@ -86,6 +90,59 @@ func TestFeedItem(t *testing.T) {
 			assert.Equal(t, "[Google](https://www.google.com)", x.Embeds[0].Description)
 		}
 	})
+	t.Run("can show podcast episode", func(t *testing.T) {
+		fi := FeedItem{
+			ArtworkURL: "https://www.example.com/artwork.jpg",
+			Duration:   "1:02:03",
+			Enclosures: []Enclosure{
+				{URL: "https://www.example.com/episode12.mp3", Type: "audio/mpeg", Length: 1_000_000},
+				{URL: "https://www.example.com/cover.jpg", Type: "image/jpeg"},
+			},
+			Episode: "S2 E12",
+			Title:   "title",
+		}
+		x, err := fi.ToDiscordMessage(false)
+		if assert.NoError(t, err) {
+			em := x.Embeds[0]
+			assert.Equal(t, "https://www.example.com/artwork.jpg", em.Thumbnail.URL)
+			assert.Equal(t, "https://www.example.com/cover.jpg", em.Image.URL)
+			if assert.Len(t, em.Fields, 3) {
+				assert.Equal(t, "Episode", em.Fields[0].Name)
+				assert.Equal(t, "S2 E12", em.Fields[0].Value)
+				assert.Equal(t, "Duration", em.Fields[1].Name)
+				assert.Equal(t, "1:02:03", em.Fields[1].Value)
+				assert.Equal(t, "Audio", em.Fields[2].Name)
+				assert.Equal(t, "[episode12.mp3](https://www.example.com/episode12.mp3) (1.0 MB)", em.Fields[2].Value)
+			}
+		}
+	})
+	t.Run("should truncate long episode and duration", func(t *testing.T) {
+		fi := FeedItem{
+			Duration: strings.Repeat("x", 2000),
+			Episode:  strings.Repeat("x", 2000),
+			Title:    "title",
+		}
+		x, err := fi.ToDiscordMessage(false)
+		if assert.NoError(t, err) {
+			em := x.Embeds[0]
+			if assert.Len(t, em.Fields, 2) {
+				assert.Len(t, []rune(em.Fields[0].Value), embedMaxFieldValueLength)
+				assert.Len(t, []rune(em.Fields[1].Value), embedMaxFieldValueLength)
+			}
+			assert.NoError(t, x.Validate())
+		}
+	})
+	t.Run("should not replace item image with image enclosure", func(t *testing.T) {
+		fi := FeedItem{
+			ImageURL:   "https://www.example.com/image.jpg",
+			Enclosures: []Enclosure{{URL: "https://www.example.com/cover.jpg", Type: "image/jpeg"}},
+		}
+		x, err := fi.ToDiscordMessage(false)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://www.example.com/image.jpg", x.Embeds[0].Image.URL)
+			assert.Len(t, x.Embeds[0].Fields, 0)
+		}
+	})
 	t.Run("can disable branding", func(t *testing.T) {
 		fi := FeedItem{Description: "description"}
 		x, err := fi.ToDiscordMessage(true)
@@ -96,6 +153,58 @@ func TestFeedItem(t *testing.T) {
 	})
 }
 
+func TestFeedItemAppearance(t *testing.T) {
+	fi := FeedItem{FeedName: "feed", IconURL: "https://www.example.com/icon.png", Title: "title"}
+	t.Run("should use custom username and avatar", func(t *testing.T) {
+		opts := RenderOptions{Appearance: config.ConfigAppearance{
+			Username:  "Security",
+			AvatarURL: "https://www.example.com/avatar.png",
+			Color:     "#1e90ff",
+		}}
+		dms, _, err := fi.ToDiscordMessages(opts)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Security", dms[0].Username)
+			assert.Equal(t, "https://www.example.com/avatar.png", dms[0].AvatarURL)
+			assert.Equal(t, 0x1e90ff, dms[0].Embeds[0].Color)
+		}
+	})
+	t.Run("should use feed icon as avatar", func(t *testing.T) {
+		opts := RenderOptions{Appearance: config.ConfigAppearance{AvatarFeedIcon: true}}
+		dms, _, err := fi.ToDiscordMessages(opts)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://www.example.com/icon.png", dms[0].AvatarURL)
+			assert.Equal(t, username, dms[0].Username)
+		}
+	})
+	t.Run("should use custom username when branding is disabled", func(t *testing.T) {
+		opts := RenderOptions{BrandingDisabled: true, Appearance: config.ConfigAppearance{Username: "Security"}}
+		dms, _, err := fi.ToDiscordMessages(opts)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Security", dms[0].Username)
+			assert.Equal(t, "", dms[0].AvatarURL)
+		}
+	})
+	t.Run("should not set color by default", func(t *testing.T) {
+		dms, _, err := fi.ToDiscordMessages(RenderOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, dms[0].Embeds[0].Color)
+		}
+	})
+}
+
+func TestMergeAppearance(t *testing.T) {
+	t.Run("should override webhook appearance with feed appearance", func(t *testing.T) {
+		base := config.ConfigAppearance{Username: "hook", AvatarURL: "https://www.example.com/a.png", Color: "hash"}
+		got := mergeAppearance(base, config.ConfigAppearance{AvatarFeedIcon: true, Color: "#000001"})
+		assert.Equal(t, config.ConfigAppearance{Username: "hook", AvatarFeedIcon: true, Color: "#000001"}, got)
+	})
+	t.Run("should keep webhook appearance when feed has none", func(t *testing.T) {
+		base := config.ConfigAppearance{Username: "hook", Color: "icon"}
+		got := mergeAppearance(base, config.ConfigAppearance{})
+		assert.Equal(t, base, got)
+	})
+}
+
 func TestTruncateString(t *testing.T) {
 	cases := []struct {
 		in        string
@@ -127,3 +236,37 @@ func TestTruncateString(t *testing.T) {
 		})
 	})
 }
+
+func TestNewFeedItem(t *testing.T) {
+	t.Run("should extract enclosures and iTunes data from item", func(t *testing.T) {
+		fp := gofeed.NewParser()
+		feed, err := fp.ParseString(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Podcast</title>
+    <item>
+      <title>Episode 12</title>
+      <enclosure url="https://www.example.com/episode12.mp3" length="1000000" type="audio/mpeg"/>
+      <itunes:duration>3723</itunes:duration>
+      <itunes:episode>12</itunes:episode>
+      <itunes:season>2</itunes:season>
+      <itunes:image href="https://www.example.com/artwork.jpg"/>
+    </item>
+  </channel>
+</rss>`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi := NewFeedItem("feed", feed, feed.Items[0], false)
+		assert.Equal(t, "1:02:03", fi.Duration)
+		assert.Equal(t, "S2 E12", fi.Episode)
+		assert.Equal(t, "https://www.example.com/artwork.jpg", fi.ArtworkURL)
+		assert.Equal(t, []Enclosure{{URL: "https://www.example.com/episode12.mp3", Type: "audio/mpeg", Length: 1000000}}, fi.Enclosures)
+	})
+	t.Run("should extract comments URL from item", func(t *testing.T) {
+		feed := &gofeed.Feed{}
+		item := &gofeed.Item{Custom: map[string]string{CustomComments: "https://www.example.com/comments"}}
+		fi := NewFeedItem("feed", feed, item, false)
+		assert.Equal(t, "https://www.example.com/comments", fi.CommentsURL)
+	})
+}
@@ -0,0 +1,100 @@
+package messenger
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// ItemImageURL returns the URL of an image for a feed item.
+// This is the item's image or otherwise the first suitable image
+// in it's content or description or in it's media extension.
+// Returns an empty string when no image was found.
+func ItemImageURL(item *gofeed.Item) string {
+	if item.Image != nil && item.Image.URL != "" {
+		return item.Image.URL
+	}
+	for _, s := range []string{item.Content, item.Description} {
+		if u := findImageInHTML(s, item.Link); u != "" {
+			return u
+		}
+	}
+	return findImageInMedia(item.Extensions)
+}
+
+// findImageInHTML returns the URL of the first suitable image in a HTML text.
+// Relative URLs are resolved against baseURL.
+func findImageInHTML(s, baseURL string) string {
+	if !strings.Contains(s, "<img") {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+	if err != nil {
+		return ""
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		base = &url.URL{}
+	}
+	var found string
+	doc.Find("img[src]").EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		if isTrackingPixel(sel) {
+			return true
+		}
+		u, err := base.Parse(strings.TrimSpace(sel.AttrOr("src", "")))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return true
+		}
+		found = u.String()
+		return false
+	})
+	return found
+}
+
+// isTrackingPixel reports wether an image element is too small to be a real image.
+func isTrackingPixel(sel *goquery.Selection) bool {
+	for _, a := range []string{"width", "height"} {
+		if v := strings.TrimSpace(sel.AttrOr(a, "")); v == "0" || v == "1" {
+			return true
+		}
+	}
+	return false
+}
+
+// findImageInMedia returns the URL of the first image in a Media RSS extension.
+func findImageInMedia(extensions ext.Extensions) string {
+	media, ok := extensions["media"]
+	if !ok {
+		return ""
+	}
+	var groups []map[string][]ext.Extension
+	groups = append(groups, media)
+	for _, g := range media["group"] {
+		groups = append(groups, g.Children)
+	}
+	for _, g := range groups {
+		for _, x := range g["thumbnail"] {
+			if u := x.Attrs["url"]; u != "" {
+				return u
+			}
+		}
+		for _, x := range g["content"] {
+			u := x.Attrs["url"]
+			if u == "" {
+				continue
+			}
+			if x.Attrs["medium"] == "image" || strings.HasPrefix(x.Attrs["type"], "image/") {
+				return u
+			}
+			for _, t := range x.Children["thumbnail"] {
+				if u := t.Attrs["url"]; u != "" {
+					return u
+				}
+			}
+		}
+	}
+	return ""
+}
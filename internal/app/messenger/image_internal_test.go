@@ -0,0 +1,62 @@
+package messenger
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemImageURL(t *testing.T) {
+	t.Run("should return item image", func(t *testing.T) {
+		item := &gofeed.Item{
+			Image:   &gofeed.Image{URL: "https://www.example.com/image.jpg"},
+			Content: `<img src="https://www.example.com/other.jpg">`,
+		}
+		assert.Equal(t, "https://www.example.com/image.jpg", ItemImageURL(item))
+	})
+	t.Run("should return first image in content", func(t *testing.T) {
+		item := &gofeed.Item{
+			Content:     `<p>alpha</p><img src="https://www.example.com/pixel.gif" width="1" height="1"><img src="/images/first.jpg"><img src="/images/second.jpg">`,
+			Description: `<img src="https://www.example.com/description.jpg">`,
+			Link:        "https://www.example.com/articles/1",
+		}
+		assert.Equal(t, "https://www.example.com/images/first.jpg", ItemImageURL(item))
+	})
+	t.Run("should return first image in description", func(t *testing.T) {
+		item := &gofeed.Item{Description: `alpha <img src="https://www.example.com/description.jpg"> bravo`}
+		assert.Equal(t, "https://www.example.com/description.jpg", ItemImageURL(item))
+	})
+	t.Run("should ignore images with data URLs", func(t *testing.T) {
+		item := &gofeed.Item{Description: `<img src="data:image/png;base64,AAAA">`}
+		assert.Equal(t, "", ItemImageURL(item))
+	})
+	t.Run("should return media thumbnail", func(t *testing.T) {
+		item := &gofeed.Item{Extensions: ext.Extensions{"media": {
+			"thumbnail": {{Attrs: map[string]string{"url": "https://www.example.com/thumb.jpg"}}},
+		}}}
+		assert.Equal(t, "https://www.example.com/thumb.jpg", ItemImageURL(item))
+	})
+	t.Run("should return media content with image type", func(t *testing.T) {
+		item := &gofeed.Item{Extensions: ext.Extensions{"media": {
+			"content": {
+				{Attrs: map[string]string{"url": "https://www.example.com/video.mp4", "medium": "video"}},
+				{Attrs: map[string]string{"url": "https://www.example.com/photo.jpg", "type": "image/jpeg"}},
+			},
+		}}}
+		assert.Equal(t, "https://www.example.com/photo.jpg", ItemImageURL(item))
+	})
+	t.Run("should return media content from group", func(t *testing.T) {
+		item := &gofeed.Item{Extensions: ext.Extensions{"media": {
+			"group": {{Children: map[string][]ext.Extension{
+				"content": {{Attrs: map[string]string{"url": "https://www.example.com/photo.jpg", "medium": "image"}}},
+			}}},
+		}}}
+		assert.Equal(t, "https://www.example.com/photo.jpg", ItemImageURL(item))
+	})
+	t.Run("should return empty string when no image found", func(t *testing.T) {
+		item := &gofeed.Item{Description: "alpha"}
+		assert.Equal(t, "", ItemImageURL(item))
+	})
+}
@@ -0,0 +1,133 @@
+package messenger
+
+import (
+	"strings"
+
+	"github.com/ErikKalkoken/go-dhook"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+const (
+	messageMaxEmbeds = 10
+	messageMaxLength = 6000 // max total length of all embeds in a message
+)
+
+// layoutDescription distributes a description over embeds and messages according to the options.
+// overhead is the length of the first embed without description.
+// readMoreURL is linked at the end of truncated descriptions when not empty.
+// Returns the description of each embed grouped by message and warnings.
+func layoutDescription(desc string, overhead int, readMoreURL string, opts RenderOptions) ([][]string, []string) {
+	warnings := make([]string, 0)
+	maxLen := max(min(embedDescriptionMaxLength, messageMaxLength-overhead), 0)
+	if opts.LongContent != config.LongContentSplit {
+		if runeLen(desc) <= maxLen {
+			return [][]string{{desc}}, warnings
+		}
+		warnings = append(warnings, "description was truncated")
+		if opts.LongContent == config.LongContentReadMore {
+			return [][]string{{truncateWithLink(desc, maxLen, readMoreURL)}}, warnings
+		}
+		return [][]string{{truncateWithLink(desc, maxLen, "")}}, warnings
+	}
+	pieces := splitParagraphs(desc, embedDescriptionMaxLength)
+	messages := [][]string{{""}}
+	used := []int{overhead} // length of each message
+	for _, p := range pieces {
+		k := len(messages) - 1
+		embeds := messages[k]
+		last := embeds[len(embeds)-1]
+		var sep string
+		if last != "" {
+			sep = "\n\n"
+		}
+		n := runeLen(sep) + runeLen(p)
+		switch {
+		case runeLen(last)+n <= embedDescriptionMaxLength && used[k]+n <= messageMaxLength:
+			embeds[len(embeds)-1] = last + sep + p
+			used[k] += n
+		case len(embeds) < messageMaxEmbeds && used[k]+runeLen(p) <= messageMaxLength:
+			messages[k] = append(embeds, p)
+			used[k] += runeLen(p)
+		default:
+			messages = append(messages, []string{p})
+			used = append(used, runeLen(p))
+		}
+	}
+	maxMessages := max(opts.MaxMessages, 1)
+	if len(messages) <= maxMessages {
+		if len(messages) > 1 {
+			warnings = append(warnings, "description was split into several messages")
+		}
+		return messages, warnings
+	}
+	warnings = append(warnings, "description was truncated")
+	messages = messages[:maxMessages]
+	k := len(messages) - 1
+	embeds := messages[k]
+	last := embeds[len(embeds)-1]
+	limit := min(embedDescriptionMaxLength, messageMaxLength-used[k]+runeLen(last))
+	embeds[len(embeds)-1] = truncateWithLink(last+"\n\n", limit, readMoreURL)
+	return messages, warnings
+}
+
+// truncateWithLink truncates a text to maxLen and adds a "Read more" link to url.
+// Adds an ellipsis instead when url is empty.
+func truncateWithLink(s string, maxLen int, url string) string {
+	if url == "" {
+		if maxLen < 3 {
+			return ""
+		}
+		s, _ = truncateString(s, maxLen)
+		return s
+	}
+	link := "\n\n[Read more](" + url + ")"
+	n := maxLen - runeLen(link)
+	if n < 3 {
+		return truncateWithLink(s, maxLen, "")
+	}
+	s, _ = truncateString(strings.TrimSpace(s), n)
+	return s + link
+}
+
+// splitParagraphs splits a markdown text at paragraph boundaries into pieces of at most maxLen.
+// Paragraphs longer then maxLen are split at line breaks or spaces or, if that is not possible, anywhere.
+func splitParagraphs(s string, maxLen int) []string {
+	pieces := make([]string, 0)
+	for _, p := range strings.Split(s, "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		for runeLen(p) > maxLen {
+			runes := []rune(p)
+			head := string(runes[:maxLen])
+			i := strings.LastIndex(head, "\n")
+			if i <= 0 {
+				i = strings.LastIndex(head, " ")
+			}
+			if i <= 0 {
+				i = len(head)
+			}
+			pieces = append(pieces, strings.TrimSpace(p[:i]))
+			p = strings.TrimSpace(p[i:])
+		}
+		if p != "" {
+			pieces = append(pieces, p)
+		}
+	}
+	return pieces
+}
+
+// embedLength returns the length of an embed as counted by Discord for the message limit.
+func embedLength(em dhook.Embed) int {
+	n := runeLen(em.Title) + runeLen(em.Description) + runeLen(em.Author.Name) + runeLen(em.Footer.Text)
+	for _, f := range em.Fields {
+		n += runeLen(f.Name) + runeLen(f.Value)
+	}
+	return n
+}
+
+func runeLen(s string) int {
+	return len([]rune(s))
+}
@@ -0,0 +1,121 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestToDiscordMessages(t *testing.T) {
+	paragraph := strings.Repeat("x", 999)
+	long := strings.Repeat(paragraph+"\n\n", 20) // ~20.000 chars
+	t.Run("should truncate long description by default", func(t *testing.T) {
+		fi := FeedItem{Description: long, ItemURL: "https://www.example.com/item", Title: "title"}
+		dms, warnings, err := fi.ToDiscordMessages(RenderOptions{})
+		if assert.NoError(t, err) {
+			assert.Len(t, dms, 1)
+			assert.Len(t, dms[0].Embeds, 1)
+			assert.Equal(t, embedDescriptionMaxLength, runeLen(dms[0].Embeds[0].Description))
+			assert.True(t, strings.HasSuffix(dms[0].Embeds[0].Description, "..."))
+			assert.Contains(t, warnings, "description was truncated")
+		}
+	})
+	t.Run("should truncate long description and add read more link", func(t *testing.T) {
+		fi := FeedItem{Description: long, ItemURL: "https://www.example.com/item", Title: "title"}
+		dms, _, err := fi.ToDiscordMessages(RenderOptions{LongContent: config.LongContentReadMore})
+		if assert.NoError(t, err) {
+			assert.Len(t, dms, 1)
+			d := dms[0].Embeds[0].Description
+			assert.LessOrEqual(t, runeLen(d), embedDescriptionMaxLength)
+			assert.True(t, strings.HasSuffix(d, "[Read more](https://www.example.com/item)"))
+			assert.NoError(t, dms[0].Validate())
+		}
+	})
+	t.Run("should split long description into embeds and messages", func(t *testing.T) {
+		fi := FeedItem{Description: long, ItemURL: "https://www.example.com/item", Title: "title", FeedName: "feed"}
+		dms, _, err := fi.ToDiscordMessages(RenderOptions{LongContent: config.LongContentSplit, MaxMessages: 10})
+		if assert.NoError(t, err) {
+			assert.Len(t, dms, 4)
+			var parts []string
+			for _, dm := range dms {
+				assert.NoError(t, dm.Validate())
+				for _, em := range dm.Embeds {
+					parts = append(parts, em.Description)
+				}
+			}
+			assert.Equal(t, strings.TrimSpace(long), strings.Join(parts, "\n\n"))
+			assert.Equal(t, "title", dms[0].Embeds[0].Title)
+			assert.Equal(t, "", dms[1].Embeds[0].Title)
+		}
+	})
+	t.Run("should truncate split description after max messages", func(t *testing.T) {
+		fi := FeedItem{Description: long, ItemURL: "https://www.example.com/item", Title: "title"}
+		dms, warnings, err := fi.ToDiscordMessages(RenderOptions{LongContent: config.LongContentSplit, MaxMessages: 2})
+		if assert.NoError(t, err) {
+			assert.Len(t, dms, 2)
+			for _, dm := range dms {
+				assert.NoError(t, dm.Validate())
+			}
+			last := dms[1].Embeds[len(dms[1].Embeds)-1].Description
+			assert.True(t, strings.HasSuffix(last, "[Read more](https://www.example.com/item)"))
+			assert.Contains(t, warnings, "description was truncated")
+		}
+	})
+	t.Run("should not split short description", func(t *testing.T) {
+		fi := FeedItem{Description: "alpha", Title: "title"}
+		dms, warnings, err := fi.ToDiscordMessages(RenderOptions{LongContent: config.LongContentSplit, MaxMessages: 3})
+		if assert.NoError(t, err) {
+			assert.Len(t, dms, 1)
+			assert.Len(t, dms[0].Embeds, 1)
+			assert.Equal(t, "alpha", dms[0].Embeds[0].Description)
+			assert.Empty(t, warnings)
+		}
+	})
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	cases := []struct {
+		in     string
+		maxLen int
+		want   []string
+	}{
+		{"alpha\n\nbravo", 10, []string{"alpha", "bravo"}},
+		{"alpha\n\n\n\nbravo\n\n", 10, []string{"alpha", "bravo"}},
+		{"alpha bravo charlie", 12, []string{"alpha bravo", "charlie"}},
+		{"alpha\nbravo charlie", 13, []string{"alpha", "bravo charlie"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"", 10, []string{}},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, splitParagraphs(tc.in, tc.maxLen))
+		})
+	}
+}
+
+func TestNewRenderOptions(t *testing.T) {
+	cfg := config.Config{
+		App:   config.ConfigApp{BrandingDisabled: true},
+		Feeds: []config.ConfigFeed{{Name: "feed", LongContent: config.LongContentSplit, MaxMessages: 5}},
+	}
+	t.Run("should return options for feed", func(t *testing.T) {
+		got := NewRenderOptions(cfg, "feed", "hook")
+		assert.Equal(t, RenderOptions{BrandingDisabled: true, LongContent: config.LongContentSplit, MaxMessages: 5}, got)
+	})
+	t.Run("should combine mentions of feed and webhook", func(t *testing.T) {
+		cfg := config.Config{
+			Feeds:    []config.ConfigFeed{{Name: "feed", Mention: &config.ConfigMention{Roles: []string{"1"}}}},
+			Webhooks: []config.ConfigWebhook{{Name: "hook", Mention: &config.ConfigMention{Users: []string{"2"}}}},
+		}
+		got := NewRenderOptions(cfg, "feed", "hook")
+		assert.Equal(t, []config.ConfigMention{{Roles: []string{"1"}}, {Users: []string{"2"}}}, got.Mentions)
+	})
+	t.Run("should return default options for unknown feed", func(t *testing.T) {
+		got := NewRenderOptions(cfg, "other", "hook")
+		assert.Equal(t, RenderOptions{BrandingDisabled: true}, got)
+	})
+}
@@ -0,0 +1,249 @@
+package messenger
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/JohannesKaufmann/html-to-markdown/escape"
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+var converter = newConverter(config.ConfigMarkdown{})
+
+var (
+	convertersMu sync.Mutex
+	converters   = make(map[string]*md.Converter) // converters by options
+)
+
+// convertDescription converts the HTML of a description to markdown according to the options.
+func convertDescription(s string, cm config.ConfigMarkdown) (string, error) {
+	var err error
+	if cm.PlainText {
+		s, err = plainText(s, cm.Remove)
+	} else {
+		s, err = converterFor(cm).ConvertString(s)
+	}
+	if err != nil {
+		return "", err
+	}
+	if cm.MaxParagraphs > 0 {
+		s = limitParagraphs(s, cm.MaxParagraphs)
+	}
+	return s, nil
+}
+
+// converterFor returns a converter for the options.
+func converterFor(cm config.ConfigMarkdown) *md.Converter {
+	if !cm.ImagesAsLinks && len(cm.Remove) == 0 && cm.Tables == "" {
+		return converter
+	}
+	key := fmt.Sprintf("%v|%q|%s", cm.ImagesAsLinks, cm.Remove, cm.Tables)
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	c, ok := converters[key]
+	if !ok {
+		c = newConverter(cm)
+		converters[key] = c
+	}
+	return c
+}
+
+// newConverter returns a new converter from HTML to markdown for the options.
+func newConverter(cm config.ConfigMarkdown) *md.Converter {
+	c := md.NewConverter("", true, nil)
+	if len(cm.Remove) > 0 || cm.Tables == config.TablesStrip {
+		c.Before(func(selec *goquery.Selection) {
+			for _, sel := range cm.Remove {
+				selec.Find(sel).Remove()
+			}
+			if cm.Tables == config.TablesStrip {
+				selec.Find("table").Remove()
+			}
+		})
+	}
+	if cm.Tables == config.TablesKeep {
+		c.AddRules(md.Rule{
+			Filter: []string{"table"},
+			Replacement: func(_ string, selec *goquery.Selection, _ *md.Options) *string {
+				return md.String("\n\n" + textTable(selec) + "\n\n")
+			},
+		})
+	}
+	imgTags := md.Rule{
+		Filter: []string{"img"},
+		Replacement: func(_ string, selec *goquery.Selection, _ *md.Options) *string {
+			if !cm.ImagesAsLinks {
+				return md.String("")
+			}
+			src := strings.TrimSpace(selec.AttrOr("src", ""))
+			if src == "" || !isValidPublicURL(src) {
+				return md.String("")
+			}
+			alt := strings.TrimSpace(selec.AttrOr("alt", ""))
+			if alt == "" {
+				alt = "Image"
+			}
+			return md.String("[" + escape.MarkdownCharacters(alt) + "](" + src + ")")
+		},
+	}
+	removeFigureTags := md.Rule{
+		Filter: []string{"figure"},
+		Replacement: func(content string, _ *goquery.Selection, _ *md.Options) *string {
+			if cm.ImagesAsLinks {
+				return md.String("\n\n" + strings.TrimSpace(content) + "\n\n")
+			}
+			return md.String("")
+		},
+	}
+	sanitizeMailToLinks := md.Rule{
+		Filter: []string{"a"},
+		Replacement: func(content string, selec *goquery.Selection, options *md.Options) *string {
+			href := selec.AttrOr("href", "#")
+			if strings.HasPrefix(href, "mailto:") {
+				return md.String(content)
+			}
+			return nil
+		},
+	}
+	sanitizeInvalidLinks := md.Rule{
+		Filter: []string{"a"},
+		Replacement: func(content string, selec *goquery.Selection, options *md.Options) *string {
+			_, err := url.ParseRequestURI(content)
+			if err == nil {
+				href := selec.AttrOr("href", "#")
+				return md.String("[Link](" + href + ")")
+			}
+			return nil
+		},
+	}
+	c.AddRules(imgTags, removeFigureTags, sanitizeMailToLinks, sanitizeInvalidLinks)
+	return c
+}
+
+// textTable returns a HTML table as text table with aligned columns in a code block.
+func textTable(table *goquery.Selection) string {
+	var rows [][]string
+	var widths []int
+	table.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		var row []string
+		tr.Children().Filter("th, td").Each(func(i int, cell *goquery.Selection) {
+			v := strings.TrimSpace(reSpaces.ReplaceAllString(strings.ReplaceAll(cell.Text(), "\n", " "), " "))
+			row = append(row, v)
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len([]rune(v)))
+		})
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("```\n")
+	for i, row := range rows {
+		var line strings.Builder
+		for j, w := range widths {
+			var v string
+			if j < len(row) {
+				v = row[j]
+			}
+			if j > 0 {
+				line.WriteString(" | ")
+			}
+			line.WriteString(v + strings.Repeat(" ", w-len([]rune(v))))
+		}
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+		if i == 0 && len(rows) > 1 && table.Find("th").Length() > 0 {
+			for j, w := range widths {
+				if j > 0 {
+					b.WriteString("-+-")
+				}
+				b.WriteString(strings.Repeat("-", w))
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+// blockElements are HTML elements, which start on a new line in plain text.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "figcaption": true, "footer": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+var (
+	reSpaces   = regexp.MustCompile(`[ \t\r\f\v]+`)
+	reNewLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// plainText returns the text of a HTML document without any formatting.
+// Elements matching the remove selectors are omitted.
+func plainText(s string, remove []string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	doc.Find("script, style, img, iframe").Remove()
+	for _, sel := range remove {
+		doc.Find(sel).Remove()
+	}
+	var b strings.Builder
+	var walk func(sel *goquery.Selection)
+	walk = func(sel *goquery.Selection) {
+		sel.Contents().Each(func(_ int, c *goquery.Selection) {
+			name := goquery.NodeName(c)
+			if name == "#text" {
+				b.WriteString(c.Text())
+				return
+			}
+			if blockElements[name] {
+				b.WriteString("\n\n")
+				defer b.WriteString("\n\n")
+			} else if name == "td" || name == "th" {
+				defer b.WriteString(" ")
+			}
+			walk(c)
+		})
+	}
+	walk(doc.Selection)
+	lines := strings.Split(b.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(reSpaces.ReplaceAllString(l, " "))
+	}
+	t := reNewLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return escape.MarkdownCharacters(strings.TrimSpace(t)), nil
+}
+
+// limitParagraphs returns the first n paragraphs of a markdown text.
+// Paragraphs within code blocks are not counted.
+func limitParagraphs(s string, n int) string {
+	var count int
+	var inCode bool
+	parts := strings.Split(s, "\n\n")
+	for i, p := range parts {
+		if strings.Count(p, "```")%2 == 1 {
+			inCode = !inCode
+		}
+		if inCode || strings.TrimSpace(p) == "" {
+			continue
+		}
+		count++
+		if count == n {
+			return strings.TrimSpace(strings.Join(parts[:i+1], "\n\n"))
+		}
+	}
+	return s
+}
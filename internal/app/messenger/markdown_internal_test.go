@@ -0,0 +1,109 @@
+package messenger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestConvertDescription(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		cm   config.ConfigMarkdown
+		want string
+	}{
+		{
+			"should remove images by default",
+			`<p>alpha <img src="https://www.example.com/a.png" alt="Chart"> bravo</p>`,
+			config.ConfigMarkdown{},
+			"alpha  bravo",
+		},
+		{
+			"should show images as links",
+			`<p>alpha <img src="https://www.example.com/a.png" alt="Chart"></p>`,
+			config.ConfigMarkdown{ImagesAsLinks: true},
+			"alpha [Chart](https://www.example.com/a.png)",
+		},
+		{
+			"should show images in figures as links",
+			`<figure><img src="https://www.example.com/a.png"><figcaption>Caption</figcaption></figure>`,
+			config.ConfigMarkdown{ImagesAsLinks: true},
+			"[Image](https://www.example.com/a.png)Caption",
+		},
+		{
+			"should remove elements matching selectors",
+			`<p>alpha</p><div class="ad">buy now</div><p class="more">Read more...</p>`,
+			config.ConfigMarkdown{Remove: []string{".ad", "p.more"}},
+			"alpha",
+		},
+		{
+			"should strip tables",
+			`<p>alpha</p><table><tr><td>1</td></tr></table>`,
+			config.ConfigMarkdown{Tables: config.TablesStrip},
+			"alpha",
+		},
+		{
+			"should keep tables as text tables",
+			`<table><tr><th>Name</th><th>Version</th></tr><tr><td>feedhook</td><td>1.0</td></tr></table>`,
+			config.ConfigMarkdown{Tables: config.TablesKeep},
+			"```\nName     | Version\n---------+--------\nfeedhook | 1.0\n```",
+		},
+		{
+			"should limit paragraphs",
+			`<p>alpha</p><p>bravo</p><p>charlie</p>`,
+			config.ConfigMarkdown{MaxParagraphs: 2},
+			"alpha\n\nbravo",
+		},
+		{
+			"should convert to plain text",
+			`<h1>Title</h1><p>alpha <b>bravo</b> <a href="https://www.example.com">charlie</a></p><ul><li>one</li><li>two</li></ul>`,
+			config.ConfigMarkdown{PlainText: true},
+			"Title\n\nalpha bravo charlie\n\none\n\ntwo",
+		},
+		{
+			"should escape markdown in plain text",
+			`<p>*alpha*</p>`,
+			config.ConfigMarkdown{PlainText: true},
+			`\*alpha\*`,
+		},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d %s", i+1, tc.name), func(t *testing.T) {
+			got, err := convertDescription(tc.in, tc.cm)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLimitParagraphs(t *testing.T) {
+	cases := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"alpha\n\nbravo\n\ncharlie", 1, "alpha"},
+		{"alpha\n\nbravo", 5, "alpha\n\nbravo"},
+		{"alpha\n\n```\ncode\n\nmore\n```\n\nbravo", 2, "alpha\n\n```\ncode\n\nmore\n```"},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, limitParagraphs(tc.in, tc.n))
+		})
+	}
+}
+
+func TestConverterFor(t *testing.T) {
+	t.Run("should return default converter for default options", func(t *testing.T) {
+		assert.Same(t, converter, converterFor(config.ConfigMarkdown{}))
+	})
+	t.Run("should reuse converter for same options", func(t *testing.T) {
+		cm := config.ConfigMarkdown{Remove: []string{".ad"}}
+		assert.Same(t, converterFor(cm), converterFor(cm))
+	})
+}
@@ -0,0 +1,51 @@
+package messenger
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/ErikKalkoken/go-dhook"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+// mentionSanitizer breaks mass mentions in texts from feeds with a zero width space.
+var mentionSanitizer = strings.NewReplacer("@everyone", "@\u200beveryone", "@here", "@\u200bhere")
+
+// sanitizeMentions returns a text where mass mentions are no longer recognized by Discord.
+func sanitizeMentions(s string) string {
+	return mentionSanitizer.Replace(s)
+}
+
+// newMentions returns the content and allowed mentions for mentioning roles and users
+// of all mention configs, which match the text.
+// The allowed mentions never permit any other mentions, e.g. @everyone.
+func newMentions(mentions []config.ConfigMention, text string) (string, *dhook.AllowedMentions) {
+	am := &dhook.AllowedMentions{Parse: []string{}}
+	text = strings.ToLower(text)
+	for _, cm := range mentions {
+		if len(cm.Keywords) > 0 && !slices.ContainsFunc(cm.Keywords, func(k string) bool {
+			return strings.Contains(text, strings.ToLower(k))
+		}) {
+			continue
+		}
+		for _, id := range cm.Roles {
+			if !slices.Contains(am.Roles, id) {
+				am.Roles = append(am.Roles, id)
+			}
+		}
+		for _, id := range cm.Users {
+			if !slices.Contains(am.Users, id) {
+				am.Users = append(am.Users, id)
+			}
+		}
+	}
+	parts := make([]string, 0, len(am.Roles)+len(am.Users))
+	for _, id := range am.Roles {
+		parts = append(parts, "<@&"+id+">")
+	}
+	for _, id := range am.Users {
+		parts = append(parts, "<@"+id+">")
+	}
+	return strings.Join(parts, " "), am
+}
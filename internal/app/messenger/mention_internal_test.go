@@ -0,0 +1,74 @@
+package messenger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestSanitizeMentions(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"alpha", "alpha"},
+		{"hi @everyone", "hi @\u200beveryone"},
+		{"@here and @here", "@\u200bhere and @\u200bhere"},
+		{"mail@example.com", "mail@example.com"},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, sanitizeMentions(tc.in))
+		})
+	}
+}
+
+func TestNewMentions(t *testing.T) {
+	t.Run("should mention roles and users", func(t *testing.T) {
+		mentions := []config.ConfigMention{{Roles: []string{"1"}, Users: []string{"2"}}}
+		content, am := newMentions(mentions, "alpha")
+		assert.Equal(t, "<@&1> <@2>", content)
+		assert.Equal(t, []string{}, am.Parse)
+		assert.Equal(t, []string{"1"}, am.Roles)
+		assert.Equal(t, []string{"2"}, am.Users)
+	})
+	t.Run("should mention only when keyword matches", func(t *testing.T) {
+		mentions := []config.ConfigMention{
+			{Roles: []string{"1"}, Keywords: []string{"CVE"}},
+			{Roles: []string{"2"}, Keywords: []string{"outage"}},
+		}
+		content, am := newMentions(mentions, "New cve published")
+		assert.Equal(t, "<@&1>", content)
+		assert.Equal(t, []string{"1"}, am.Roles)
+	})
+	t.Run("should not mention same role twice", func(t *testing.T) {
+		mentions := []config.ConfigMention{{Roles: []string{"1"}}, {Roles: []string{"1"}}}
+		content, _ := newMentions(mentions, "alpha")
+		assert.Equal(t, "<@&1>", content)
+	})
+	t.Run("should allow no mentions when nothing is configured", func(t *testing.T) {
+		content, am := newMentions(nil, "alpha @everyone")
+		assert.Equal(t, "", content)
+		assert.Equal(t, []string{}, am.Parse)
+		assert.Empty(t, am.Roles)
+		assert.Empty(t, am.Users)
+	})
+}
+
+func TestToDiscordMessagesWithMentions(t *testing.T) {
+	t.Run("should add mentions to first message and sanitize feed text", func(t *testing.T) {
+		fi := FeedItem{Description: "Hello @everyone", Title: "@here: advisory"}
+		opts := RenderOptions{Mentions: []config.ConfigMention{{Roles: []string{"123"}}}}
+		dms, _, err := fi.ToDiscordMessages(opts)
+		if assert.NoError(t, err) {
+			dm := dms[0]
+			assert.Equal(t, "<@&123>", dm.Content)
+			assert.Equal(t, []string{"123"}, dm.AllowedMentions.Roles)
+			assert.Equal(t, "Hello @\u200beveryone", dm.Embeds[0].Description)
+			assert.Equal(t, "@\u200bhere: advisory", dm.Embeds[0].Title)
+		}
+	})
+}
@@ -12,17 +12,23 @@ import (
 type Message struct {
 	Attempt   int
 	Item      FeedItem
+	Sent      int // number of Discord messages already sent for the item, e.g. parts of a split description
 	Timestamp time.Time
 }
 
 // newMessage returns a new message from a feed item.
 func newMessage(feedName string, feed *gofeed.Feed, item *gofeed.Item, isUpdated bool) (Message, error) {
 	fi := NewFeedItem(feedName, feed, item, isUpdated)
+	return newMessageFromFeedItem(fi), nil
+}
+
+// newMessageFromFeedItem returns a new message from a FeedItem.
+func newMessageFromFeedItem(fi FeedItem) Message {
 	m := Message{
 		Item:      fi,
 		Timestamp: time.Now().UTC(),
 	}
-	return m, nil
+	return m
 }
 
 func newMessageFromBytes(byt []byte) (Message, error) {
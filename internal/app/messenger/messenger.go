@@ -26,28 +26,35 @@ import (
 // Unsent messages are queued and will be picked up again after a process restart.
 type Messenger struct {
 	cfg      config.Config
+	client   *dhook.Client
 	shutdown chan struct{} // commence shutdown
 	done     chan struct{} // shutdown completed
-	dwh      *dhook.Webhook
 	errCount atomic.Int64
 	name     string
 	queue    *pqueue.PQueue
 	st       *storage.Storage
+	url      string
+	webhooks map[string]*dhook.Webhook // webhooks by URL. Only used by the main goroutine.
 
 	mu        sync.Mutex
 	isRunning bool
+
+	pauseMu sync.Mutex
+	resumed chan struct{} // closed when a paused messenger is resumed. nil when not paused.
 }
 
 // NewMessenger returns a new Messenger.
 func NewMessenger(client *dhook.Client, queue *pqueue.PQueue, name, url string, st *storage.Storage, cfg config.Config) *Messenger {
 	mg := &Messenger{
 		cfg:      cfg,
+		client:   client,
 		shutdown: make(chan struct{}),
 		done:     make(chan struct{}),
-		dwh:      client.NewWebhook(url),
 		name:     name,
 		queue:    queue,
 		st:       st,
+		url:      url,
+		webhooks: map[string]*dhook.Webhook{url: client.NewWebhook(url)},
 	}
 	return mg
 }
@@ -65,10 +72,55 @@ func (mg *Messenger) AddMessage(feedName string, feed *gofeed.Feed, item *gofeed
 	return mg.queue.Put(v)
 }
 
+// AddFeedItem adds a new message for a feed item for being send to to webhook
+func (mg *Messenger) AddFeedItem(fi FeedItem) error {
+	v, err := newMessageFromFeedItem(fi).toBytes()
+	if err != nil {
+		return err
+	}
+	return mg.queue.Put(v)
+}
+
 func (mg *Messenger) Name() string {
 	return mg.name
 }
 
+// SetPaused pauses or resumes sending of messages.
+// A paused messenger keeps accepting new messages into it's queue.
+func (mg *Messenger) SetPaused(paused bool) {
+	mg.pauseMu.Lock()
+	defer mg.pauseMu.Unlock()
+	if paused && mg.resumed == nil {
+		mg.resumed = make(chan struct{})
+	} else if !paused && mg.resumed != nil {
+		close(mg.resumed)
+		mg.resumed = nil
+	}
+}
+
+// IsPaused reports wether the messenger is paused.
+func (mg *Messenger) IsPaused() bool {
+	mg.pauseMu.Lock()
+	defer mg.pauseMu.Unlock()
+	return mg.resumed != nil
+}
+
+// waitWhilePaused blocks while the messenger is paused or until the context is canceled.
+func (mg *Messenger) waitWhilePaused(ctx context.Context) error {
+	mg.pauseMu.Lock()
+	resumed := mg.resumed
+	mg.pauseMu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
+
 // Shutdown conducts a graceful shutdown of a messenger and frees it's resources.
 // Reports wether a shutdown was actually conducted.
 func (mg *Messenger) Shutdown() bool {
@@ -85,6 +137,10 @@ func (mg *Messenger) Shutdown() bool {
 
 // Start starts the service.
 func (mg *Messenger) Start() error {
+	paused, err := mg.st.IsWebhookPaused(mg.name)
+	if err != nil {
+		return err
+	}
 	if err := func() error {
 		mg.mu.Lock()
 		defer mg.mu.Unlock()
@@ -96,7 +152,7 @@ func (mg *Messenger) Start() error {
 	}(); err != nil {
 		return err
 	}
-
+	mg.SetPaused(paused)
 	ctx, cancel := context.WithCancel(context.Background())
 	stopped := make(chan struct{})
 	// shutdown main goroutine when signal is received
@@ -109,9 +165,13 @@ func (mg *Messenger) Start() error {
 	// main goroutine
 	go func() {
 		myLog := slog.With("messenger", mg.name)
-		myLog.Info("Started", "queued", mg.queue.Size())
+		myLog.Info("Started", "queued", mg.queue.Size(), "paused", mg.IsPaused())
 	loop:
 		for {
+			if err := mg.waitWhilePaused(ctx); err == context.Canceled {
+				myLog.Debug("canceled")
+				break
+			}
 			v, err := mg.queue.GetWithContext(ctx)
 			if err == context.Canceled {
 				myLog.Debug("canceled")
@@ -120,46 +180,67 @@ func (mg *Messenger) Start() error {
 				myLog.Error("Failed to read from queue", "error", err)
 				continue
 			}
+			// the messenger might have been paused while waiting for the queue
+			if err := mg.waitWhilePaused(ctx); err == context.Canceled {
+				myLog.Debug("canceled")
+				mg.requeue(v)
+				break
+			}
 			m, err := newMessageFromBytes(v)
 			if err != nil {
 				myLog.Error("Failed to de-serialize message. Discarding", "error", err, "data", string(v))
 				continue
 			}
-			dm, err := m.Item.ToDiscordMessage(mg.cfg.App.BrandingDisabled)
+			opts := NewRenderOptions(mg.cfg, m.Item.FeedName, mg.name)
+			dms, warnings, err := m.Item.ToDiscordMessages(opts)
 			if err != nil {
 				myLog.Error("Failed to convert message for Discord. Discarding", "error", err, "message", m)
 				continue
 			}
-			if err := dm.Validate(); err != nil {
-				myLog.Error("Discord Message not valid. Discarding", "error", err, "message", dm)
+			for _, w := range warnings {
+				myLog.Warn(w, "title", m.Item.Title)
+			}
+			if err := validateMessages(dms); err != nil {
+				myLog.Error("Discord Message not valid. Discarding", "error", err, "message", dms)
 				continue
 			}
-			var attempt int
-			for {
-				if ctx.Err() == context.Canceled {
-					myLog.Debug("Canceled")
-					break loop
-				}
-				attempt++
-				_, err = mg.dwh.Execute(dm, nil)
-				if err == nil {
-					break
-				}
-				mg.errCount.Add(1)
-				errHTTP, ok := err.(dhook.HTTPError)
-				if ok && errHTTP.Status == http.StatusBadRequest {
-					myLog.Error("Bad request. Discarding", "error", err, "message", dm)
-					break
+			wh := mg.webhook(opts.ThreadID, len(opts.Buttons) > 0)
+		messages:
+			for i := m.Sent; i < len(dms); i++ {
+				dm := dms[i]
+				var attempt int
+				for {
+					if err := mg.waitWhilePaused(ctx); err == context.Canceled || ctx.Err() == context.Canceled {
+						myLog.Debug("Canceled")
+						m.Sent = i
+						if v, err := m.toBytes(); err != nil {
+							myLog.Error("Failed to serialize message. Discarding", "error", err, "message", m)
+						} else {
+							mg.requeue(v)
+						}
+						break loop
+					}
+					attempt++
+					err = ExecuteOrRecord(mg.cfg, wh, mg.name, dm)
+					if err == nil {
+						break
+					}
+					mg.errCount.Add(1)
+					errHTTP, ok := err.(dhook.HTTPError)
+					if ok && errHTTP.Status == http.StatusBadRequest {
+						myLog.Error("Bad request. Discarding", "error", err, "message", dm)
+						break messages
+					}
+					err429, ok := err.(dhook.TooManyRequestsError)
+					if ok {
+						myLog.Error("API rate limited exceeded", "retryAfter", err429.RetryAfter)
+						time.Sleep(err429.RetryAfter)
+						continue
+					}
+					d := maxBackoffJitter(attempt)
+					myLog.Error("Failed to send to webhook. Retrying.", "error", err, "attempt", attempt, "wait", d, "message", dm)
+					time.Sleep(d)
 				}
-				err429, ok := err.(dhook.TooManyRequestsError)
-				if ok {
-					myLog.Error("API rate limited exceeded", "retryAfter", err429.RetryAfter)
-					time.Sleep(err429.RetryAfter)
-					continue
-				}
-				d := maxBackoffJitter(attempt)
-				myLog.Error("Failed to send to webhook. Retrying.", "error", err, "attempt", attempt, "wait", d, "message", dm)
-				time.Sleep(d)
 			}
 			if err := mg.st.UpdateWebhookStats(mg.name, func(ws *app.WebhookStats) error {
 				ws.SentCount++
@@ -176,6 +257,36 @@ func (mg *Messenger) Start() error {
 	return nil
 }
 
+// webhook returns the webhook for posting into a thread and for posting messages with components.
+// Webhooks are created once and then reused.
+func (mg *Messenger) webhook(threadID string, withComponents bool) *dhook.Webhook {
+	u := WebhookURL(mg.url, threadID, withComponents)
+	wh, ok := mg.webhooks[u]
+	if !ok {
+		wh = mg.client.NewWebhook(u)
+		mg.webhooks[u] = wh
+	}
+	return wh
+}
+
+// requeue puts a message which has not been sent completely back to the front of the queue,
+// so that it is sent first when the messenger is started again.
+func (mg *Messenger) requeue(v []byte) {
+	if err := mg.queue.PutFront(v); err != nil {
+		slog.Error("Failed to requeue message", "messenger", mg.name, "error", err)
+	}
+}
+
+// validateMessages validates all messages and returns the first error.
+func validateMessages(dms []dhook.Message) error {
+	for _, dm := range dms {
+		if err := dm.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func maxBackoffJitter(attempt int) time.Duration {
 	const (
 		base     = 100
@@ -190,12 +301,14 @@ func maxBackoffJitter(attempt int) time.Duration {
 type Status struct {
 	QueueSize  int
 	ErrorCount int
+	IsPaused   bool
 }
 
 func (mg *Messenger) Status() Status {
 	x := Status{
 		QueueSize:  mg.queue.Size(),
 		ErrorCount: int(mg.errCount.Load()),
+		IsPaused:   mg.IsPaused(),
 	}
 	return x
 }
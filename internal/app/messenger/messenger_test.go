@@ -1,7 +1,11 @@
 package messenger_test
 
 import (
+	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -67,6 +71,146 @@ func TestMessenger(t *testing.T) {
 		}
 		mg.Shutdown()
 	})
+	t.Run("should queue but not send messages while paused", func(t *testing.T) {
+		st.ClearWebhookStats()
+		q.Clear()
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"POST",
+			"https://www.example.com",
+			httpmock.NewStringResponder(204, ""),
+		)
+		mg := messenger.NewMessenger(c, q, "dummy", "https://www.example.com", st, config.Config{})
+		if err := st.SetWebhookPaused("dummy", true); err != nil {
+			t.Fatal(err)
+		}
+		defer st.SetWebhookPaused("dummy", false)
+		if err := mg.Start(); err != nil {
+			t.Fatal(err)
+		}
+		feed := &gofeed.Feed{Title: "title"}
+		now := time.Now()
+		item := &gofeed.Item{Content: "content", PublishedParsed: &now}
+		err = mg.AddMessage("dummy", feed, item, false)
+		time.Sleep(500 * time.Millisecond)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, httpmock.GetTotalCallCount())
+			assert.Equal(t, 1, mg.Status().QueueSize)
+			assert.True(t, mg.Status().IsPaused)
+		}
+		mg.SetPaused(false)
+		time.Sleep(500 * time.Millisecond)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+		mg.Shutdown()
+	})
+	t.Run("should not send messages when paused while waiting for the queue", func(t *testing.T) {
+		st.ClearWebhookStats()
+		q.Clear()
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"POST",
+			"https://www.example.com",
+			httpmock.NewStringResponder(204, ""),
+		)
+		mg := messenger.NewMessenger(c, q, "dummy", "https://www.example.com", st, config.Config{})
+		if err := mg.Start(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		mg.SetPaused(true)
+		feed := &gofeed.Feed{Title: "title"}
+		now := time.Now()
+		item := &gofeed.Item{Content: "content", PublishedParsed: &now}
+		err = mg.AddMessage("dummy", feed, item, false)
+		time.Sleep(500 * time.Millisecond)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, httpmock.GetTotalCallCount())
+		}
+		mg.SetPaused(false)
+		time.Sleep(500 * time.Millisecond)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+		mg.Shutdown()
+	})
+	t.Run("should resume split item with unsent parts after shutdown", func(t *testing.T) {
+		st.ClearWebhookStats()
+		q.Clear()
+		httpmock.Reset()
+		var sent atomic.Int64
+		var failing atomic.Bool
+		httpmock.RegisterResponder("POST", "https://www.example.com", func(req *http.Request) (*http.Response, error) {
+			if failing.Load() {
+				return httpmock.NewStringResponse(500, ""), nil
+			}
+			if sent.Add(1) == 1 {
+				failing.Store(true)
+			}
+			return httpmock.NewStringResponse(204, ""), nil
+		})
+		cfg := config.Config{Feeds: []config.ConfigFeed{{Name: "dummy", LongContent: config.LongContentSplit, MaxMessages: 5}}}
+		feed := &gofeed.Feed{Title: "title"}
+		now := time.Now()
+		item := &gofeed.Item{Content: strings.Repeat(strings.Repeat("x", 999)+"\n\n", 20), PublishedParsed: &now}
+		dms, _, err := messenger.NewFeedItem("dummy", feed, item, false).ToDiscordMessages(messenger.NewRenderOptions(cfg, "dummy", "dummy"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dms) < 2 {
+			t.Fatalf("expected split item, got %d messages", len(dms))
+		}
+		mg := messenger.NewMessenger(c, q, "dummy", "https://www.example.com", st, cfg)
+		if err := mg.Start(); err != nil {
+			t.Fatal(err)
+		}
+		if err := mg.AddMessage("dummy", feed, item, false); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(500 * time.Millisecond)
+		mg.Shutdown()
+		assert.Equal(t, int64(1), sent.Load())
+		assert.Equal(t, 1, q.Size())
+		ws, err := st.GetWebhookStats("dummy")
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, ws.SentCount)
+		}
+		failing.Store(false)
+		mg = messenger.NewMessenger(c, q, "dummy", "https://www.example.com", st, cfg)
+		if err := mg.Start(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(500 * time.Millisecond)
+		mg.Shutdown()
+		assert.Equal(t, int64(len(dms)), sent.Load())
+		assert.Equal(t, 0, q.Size())
+		ws, err = st.GetWebhookStats("dummy")
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, ws.SentCount)
+		}
+	})
+	t.Run("should record messages instead of sending them in dry-run mode", func(t *testing.T) {
+		st.ClearWebhookStats()
+		q.Clear()
+		httpmock.Reset()
+		fn := filepath.Join(t.TempDir(), "dryrun.jsonl")
+		cfg := config.Config{App: config.ConfigApp{DryRun: true, DryRunFile: fn}}
+		mg := messenger.NewMessenger(c, q, "dummy", "https://www.example.com", st, cfg)
+		if err := mg.Start(); err != nil {
+			t.Fatal(err)
+		}
+		feed := &gofeed.Feed{Title: "title"}
+		now := time.Now()
+		item := &gofeed.Item{Content: "content", PublishedParsed: &now}
+		err = mg.AddMessage("dummy", feed, item, false)
+		time.Sleep(500 * time.Millisecond)
+		mg.Shutdown()
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, httpmock.GetTotalCallCount())
+			data, err := os.ReadFile(fn)
+			if assert.NoError(t, err) {
+				assert.Contains(t, string(data), `"webhook":"dummy"`)
+				assert.Contains(t, string(data), "content")
+			}
+		}
+	})
 	t.Run("can submit messages 2", func(t *testing.T) {
 		st.ClearWebhookStats()
 		q.Clear()
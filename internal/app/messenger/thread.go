@@ -0,0 +1,46 @@
+package messenger
+
+import (
+	"html"
+	"net/url"
+)
+
+const threadNameMaxLength = 100
+
+// WebhookURL returns the URL of a webhook for posting into a thread
+// and for posting messages with components, e.g. link buttons.
+// Returns the URL unchanged when threadID is empty and withComponents is false.
+func WebhookURL(rawURL, threadID string, withComponents bool) string {
+	if threadID == "" && !withComponents {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	if threadID != "" {
+		q.Set("thread_id", threadID)
+	}
+	if withComponents {
+		q.Set("with_components", "true")
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// threadName returns the name of a forum post for an item.
+func (fi FeedItem) threadName() string {
+	var s string
+	for _, x := range []string{fi.Title, fi.FeedTitle, fi.FeedName} {
+		if x != "" {
+			s = html.UnescapeString(x)
+			break
+		}
+	}
+	if s == "" {
+		s = "Untitled"
+	}
+	s, _ = truncateString(s, threadNameMaxLength)
+	return s
+}
@@ -0,0 +1,128 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ErikKalkoken/go-dhook"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestWebhookURL(t *testing.T) {
+	cases := []struct {
+		url            string
+		threadID       string
+		withComponents bool
+		want           string
+	}{
+		{"https://discord.com/api/webhooks/1/abc", "", false, "https://discord.com/api/webhooks/1/abc"},
+		{"https://discord.com/api/webhooks/1/abc", "123", false, "https://discord.com/api/webhooks/1/abc?thread_id=123"},
+		{"https://discord.com/api/webhooks/1/abc?wait=true", "123", false, "https://discord.com/api/webhooks/1/abc?thread_id=123&wait=true"},
+		{"https://discord.com/api/webhooks/1/abc", "", true, "https://discord.com/api/webhooks/1/abc?with_components=true"},
+		{"https://discord.com/api/webhooks/1/abc", "123", true, "https://discord.com/api/webhooks/1/abc?thread_id=123&with_components=true"},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, WebhookURL(tc.url, tc.threadID, tc.withComponents))
+		})
+	}
+}
+
+func TestMessengerWebhook(t *testing.T) {
+	mg := NewMessenger(dhook.NewClient(), nil, "hook", "https://discord.com/api/webhooks/1/abc", nil, config.Config{})
+	t.Run("should reuse webhooks", func(t *testing.T) {
+		assert.Same(t, mg.webhook("", false), mg.webhook("", false))
+		assert.Same(t, mg.webhook("123", false), mg.webhook("123", false))
+	})
+	t.Run("should create separate webhooks for threads", func(t *testing.T) {
+		assert.NotSame(t, mg.webhook("", false), mg.webhook("123", false))
+		assert.NotSame(t, mg.webhook("123", false), mg.webhook("456", false))
+		assert.NotSame(t, mg.webhook("123", false), mg.webhook("123", true))
+	})
+}
+
+func TestThreadName(t *testing.T) {
+	cases := []struct {
+		fi   FeedItem
+		want string
+	}{
+		{FeedItem{Title: "Tom &amp; Jerry", FeedName: "feed"}, "Tom & Jerry"},
+		{FeedItem{FeedTitle: "Feed Title", FeedName: "feed"}, "Feed Title"},
+		{FeedItem{FeedName: "feed"}, "feed"},
+		{FeedItem{}, "Untitled"},
+		{FeedItem{Title: strings.Repeat("x", 120)}, strings.Repeat("x", 97) + "..."},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.fi.threadName())
+		})
+	}
+}
+
+func TestToDiscordMessagesForForum(t *testing.T) {
+	t.Run("should create forum post with thread name and tags", func(t *testing.T) {
+		fi := FeedItem{Description: "alpha", Title: "title"}
+		dms, _, err := fi.ToDiscordMessages(RenderOptions{Forum: true, ForumTags: []string{"1"}})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "title", dms[0].ThreadName)
+			assert.Equal(t, []string{"1"}, dms[0].AppliedTags)
+		}
+	})
+	t.Run("should post split content as single message to forum", func(t *testing.T) {
+		fi := FeedItem{Description: strings.Repeat(strings.Repeat("x", 999)+"\n\n", 20), Title: "title"}
+		dms, _, err := fi.ToDiscordMessages(RenderOptions{Forum: true, LongContent: config.LongContentSplit, MaxMessages: 5})
+		if assert.NoError(t, err) {
+			assert.Len(t, dms, 1)
+			assert.NoError(t, dms[0].Validate())
+		}
+	})
+	t.Run("should not set thread name when not in forum mode", func(t *testing.T) {
+		fi := FeedItem{Description: "alpha", Title: "title"}
+		dms, _, err := fi.ToDiscordMessages(RenderOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "", dms[0].ThreadName)
+			assert.Empty(t, dms[0].AppliedTags)
+		}
+	})
+}
+
+func TestNewRenderOptionsForThreads(t *testing.T) {
+	t.Run("should use forum settings from webhook", func(t *testing.T) {
+		cfg := config.Config{
+			Feeds:    []config.ConfigFeed{{Name: "feed"}},
+			Webhooks: []config.ConfigWebhook{{Name: "hook", Forum: true, ForumTags: []string{"1"}}},
+		}
+		got := NewRenderOptions(cfg, "feed", "hook")
+		assert.True(t, got.Forum)
+		assert.Equal(t, []string{"1"}, got.ForumTags)
+	})
+	t.Run("should override forum tags with feed tags", func(t *testing.T) {
+		cfg := config.Config{
+			Feeds:    []config.ConfigFeed{{Name: "feed", ForumTags: []string{"2"}}},
+			Webhooks: []config.ConfigWebhook{{Name: "hook", Forum: true, ForumTags: []string{"1"}}},
+		}
+		got := NewRenderOptions(cfg, "feed", "hook")
+		assert.Equal(t, []string{"2"}, got.ForumTags)
+	})
+	t.Run("should post into thread of feed instead of forum", func(t *testing.T) {
+		cfg := config.Config{
+			Feeds:    []config.ConfigFeed{{Name: "feed", ThreadID: "3"}},
+			Webhooks: []config.ConfigWebhook{{Name: "hook", Forum: true, ForumTags: []string{"1"}}},
+		}
+		got := NewRenderOptions(cfg, "feed", "hook")
+		assert.False(t, got.Forum)
+		assert.Equal(t, "3", got.ThreadID)
+		assert.Empty(t, got.ForumTags)
+	})
+	t.Run("should use thread of webhook", func(t *testing.T) {
+		cfg := config.Config{
+			Feeds:    []config.ConfigFeed{{Name: "feed"}},
+			Webhooks: []config.ConfigWebhook{{Name: "hook", ThreadID: "4"}},
+		}
+		got := NewRenderOptions(cfg, "feed", "hook")
+		assert.Equal(t, "4", got.ThreadID)
+	})
+}
@@ -0,0 +1,55 @@
+// Package ogimage fetches Open Graph images of web pages.
+package ogimage
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/cachedfetch"
+)
+
+const maxPageSize = 1024 * 1024 // the head of a page is usually within this size
+
+// Fetcher fetches the Open Graph images of web pages.
+// Results are cached, including pages without image.
+type Fetcher struct {
+	f *cachedfetch.Fetcher[string]
+}
+
+// New returns a new Fetcher for Open Graph images. Requests are aborted after timeout.
+func New(client *http.Client, clock cachedfetch.Clock, timeout time.Duration, userAgent string) *Fetcher {
+	return &Fetcher{f: cachedfetch.New(client, clock, timeout, userAgent, parseImageURL)}
+}
+
+// ImageURL returns the URL of the Open Graph image of a web page.
+// Returns an empty string when the page has no image.
+func (f *Fetcher) ImageURL(pageURL string) (string, error) {
+	return f.f.Get(pageURL)
+}
+
+// parseImageURL returns the URL of the Open Graph image from the response for a page.
+func parseImageURL(resp *http.Response) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return "", err
+	}
+	var s string
+	for _, sel := range []string{`meta[property="og:image"]`, `meta[property="og:image:url"]`, `meta[name="twitter:image"]`} {
+		s = strings.TrimSpace(doc.Find(sel).First().AttrOr("content", ""))
+		if s != "" {
+			break
+		}
+	}
+	if s == "" {
+		return "", nil
+	}
+	u, err := resp.Request.URL.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", nil
+	}
+	return u.String(), nil
+}
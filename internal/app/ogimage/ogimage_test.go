@@ -0,0 +1,87 @@
+package ogimage_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/ogimage"
+)
+
+type fakeTime struct {
+	now time.Time
+}
+
+func (rt fakeTime) Now() time.Time {
+	return rt.now
+}
+
+func TestFetcher(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	clock := fakeTime{now: time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)}
+	t.Run("should return open graph image of page", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/article",
+			httpmock.NewStringResponder(200, `<html><head><meta property="og:image" content="/images/cover.jpg"></head></html>`),
+		)
+		f := ogimage.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		got, err := f.ImageURL("https://www.example.com/article")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://www.example.com/images/cover.jpg", got)
+		}
+	})
+	t.Run("should fall back to twitter image", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/article",
+			httpmock.NewStringResponder(200, `<html><head><meta name="twitter:image" content="https://cdn.example.com/x.png"></head></html>`),
+		)
+		f := ogimage.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		got, err := f.ImageURL("https://www.example.com/article")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://cdn.example.com/x.png", got)
+		}
+	})
+	t.Run("should return empty string when page has no image", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/article",
+			httpmock.NewStringResponder(200, `<html><head><title>Article</title></head></html>`),
+		)
+		f := ogimage.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		got, err := f.ImageURL("https://www.example.com/article")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "", got)
+		}
+	})
+	t.Run("should cache results", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			"GET",
+			"https://www.example.com/article",
+			httpmock.NewStringResponder(200, `<html><head><meta property="og:image" content="https://www.example.com/a.jpg"></head></html>`),
+		)
+		f := ogimage.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		for range 2 {
+			if _, err := f.ImageURL("https://www.example.com/article"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+	t.Run("should return error when page can not be fetched", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://www.example.com/article", httpmock.NewStringResponder(404, ""))
+		f := ogimage.New(http.DefaultClient, clock, 5*time.Second, "agent")
+		_, err := f.ImageURL("https://www.example.com/article")
+		assert.Error(t, err)
+	})
+}
@@ -17,8 +17,11 @@ const (
 
 // ProcessedItem represents a sent item
 type ProcessedItem struct {
-	ID        string
-	Published time.Time
+	ID          string
+	Published   time.Time
+	Updated     time.Time // zero when unknown
+	ContentHash string    // empty when unknown
+	Recorded    time.Time // when the item was last recorded. Zero when unknown.
 }
 
 func (si *ProcessedItem) Key() []byte {
@@ -24,6 +24,42 @@ func (c Client) CheckConfig() error {
 	return rc.Call("RemoteService.CheckConfig", EmptyArgs{}, &reply)
 }
 
+func (c Client) PauseFeed(feedName string) error {
+	return c.callFeed("RemoteService.PauseFeed", feedName)
+}
+
+func (c Client) ResumeFeed(feedName string) error {
+	return c.callFeed("RemoteService.ResumeFeed", feedName)
+}
+
+func (c Client) PauseWebhook(webhookName string) error {
+	return c.callWebhook("RemoteService.PauseWebhook", webhookName)
+}
+
+func (c Client) ResumeWebhook(webhookName string) error {
+	return c.callWebhook("RemoteService.ResumeWebhook", webhookName)
+}
+
+func (c Client) callFeed(method, feedName string) error {
+	rc, err := c.dial()
+	if err != nil {
+		return err
+	}
+	args := FeedArgs{FeedName: feedName}
+	var reply bool
+	return rc.Call(method, args, &reply)
+}
+
+func (c Client) callWebhook(method, webhookName string) error {
+	rc, err := c.dial()
+	if err != nil {
+		return err
+	}
+	args := WebhookArgs{WebhookName: webhookName}
+	var reply bool
+	return rc.Call(method, args, &reply)
+}
+
 func (c Client) PostLatestFeedItem(feedName string) error {
 	rc, err := c.dial()
 	if err != nil {
@@ -34,6 +70,19 @@ func (c Client) PostLatestFeedItem(feedName string) error {
 	return rc.Call("RemoteService.PostLatestFeedItem", args, &reply)
 }
 
+func (c Client) Preview(url string, limit int) (string, error) {
+	rc, err := c.dial()
+	if err != nil {
+		return "", err
+	}
+	args := PreviewArgs{URL: url, Limit: limit}
+	var reply string
+	if err := rc.Call("RemoteService.Preview", args, &reply); err != nil {
+		return "", fmt.Errorf("call: %w", err)
+	}
+	return reply, nil
+}
+
 func (c Client) Restart() error {
 	rc, err := c.dial()
 	if err != nil {
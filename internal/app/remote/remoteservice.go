@@ -10,6 +10,7 @@ import (
 
 	"github.com/ErikKalkoken/feedhook/internal/app/config"
 	"github.com/ErikKalkoken/feedhook/internal/app/dispatcher"
+	"github.com/ErikKalkoken/feedhook/internal/app/messenger"
 	"github.com/ErikKalkoken/feedhook/internal/app/storage"
 	"github.com/ErikKalkoken/feedhook/internal/consoletable"
 	"github.com/ErikKalkoken/go-dhook"
@@ -25,11 +26,23 @@ type SendLatestArgs struct {
 	FeedName string
 }
 
+type PreviewArgs struct {
+	URL   string
+	Limit int
+}
+
+type FeedArgs struct {
+	FeedName string
+}
+
+type WebhookArgs struct {
+	WebhookName string
+}
+
 // RemoteService is a service for providing remote access to the app via RPC.
 type RemoteService struct {
 	cfg        config.Config
 	configPath string
-	client     *dhook.Client
 	d          *dispatcher.Dispatcher
 	st         *storage.Storage
 }
@@ -37,7 +50,6 @@ type RemoteService struct {
 func NewRemoteService(d *dispatcher.Dispatcher, st *storage.Storage, cfg config.Config, configPath string) *RemoteService {
 	x := &RemoteService{
 		cfg:        cfg,
-		client:     dhook.NewClient(),
 		d:          d,
 		st:         st,
 		configPath: configPath,
@@ -50,20 +62,68 @@ func (s *RemoteService) CheckConfig(args *EmptyArgs, reply *bool) error {
 	return err
 }
 
+func (s *RemoteService) PauseFeed(args *FeedArgs, reply *bool) error {
+	return s.d.SetFeedPaused(args.FeedName, true)
+}
+
+func (s *RemoteService) ResumeFeed(args *FeedArgs, reply *bool) error {
+	return s.d.SetFeedPaused(args.FeedName, false)
+}
+
+func (s *RemoteService) PauseWebhook(args *WebhookArgs, reply *bool) error {
+	return s.d.SetWebhookPaused(args.WebhookName, true)
+}
+
+func (s *RemoteService) ResumeWebhook(args *WebhookArgs, reply *bool) error {
+	return s.d.SetWebhookPaused(args.WebhookName, false)
+}
+
 func (s *RemoteService) PostLatestFeedItem(args *SendLatestArgs, reply *bool) error {
 	return s.d.PostLatestFeedItem(args.FeedName)
 }
 
+func (s *RemoteService) Preview(args *PreviewArgs, reply *string) error {
+	title, previews, err := s.d.PreviewFeed(args.URL, args.Limit)
+	if err != nil {
+		return err
+	}
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "Feed: %s\n", title)
+	for i, p := range previews {
+		fmt.Fprintf(out, "\n#%d %s\n", i+1, p.Title)
+		if p.SkipReason != "" {
+			fmt.Fprintf(out, "Would be skipped: %s\n", p.SkipReason)
+		}
+		for _, w := range p.Warnings {
+			fmt.Fprintf(out, "Warning: %s\n", w)
+		}
+		if p.ConversionError != "" {
+			fmt.Fprintf(out, "Conversion error: %s\n", p.ConversionError)
+			continue
+		}
+		if p.ValidationError != "" {
+			fmt.Fprintf(out, "Validation error: %s\n", p.ValidationError)
+		}
+		fmt.Fprintln(out, p.Payload)
+	}
+	*reply = out.String()
+	return nil
+}
+
 func (s *RemoteService) Restart(args *EmptyArgs, reply *bool) error {
 	return s.d.Restart()
 }
 
 func (s *RemoteService) Statistics(args *EmptyArgs, reply *string) error {
 	out := &strings.Builder{}
+	if s.cfg.App.DryRun {
+		fmt.Fprintln(out, "DRY-RUN MODE: Messages are recorded, but not sent to Discord")
+		fmt.Fprintln(out)
+	}
 	// Feed stats
-	feedsTable := consoletable.New("Feeds", 6)
+	feedsTable := consoletable.New("Feeds", 9)
 	feedsTable.Target = out
-	feedsTable.AddRow([]any{"Name", "Enabled", "Webhooks", "Received", "Last", "Errors"})
+	feedsTable.AddRow([]any{"Name", "Enabled", "Paused", "Health", "Next poll", "Webhooks", "Received", "Last", "Errors"})
 	slices.SortFunc(s.cfg.Feeds, func(a, b config.ConfigFeed) int {
 		return cmp.Compare(a.Name, b.Name)
 	})
@@ -74,14 +134,18 @@ func (s *RemoteService) Statistics(args *EmptyArgs, reply *string) error {
 		} else if err != nil {
 			return err
 		}
-		feedsTable.AddRow([]any{o.Name, !cf.Disabled, cf.Webhooks, o.ReceivedCount, o.ReceivedLast, o.ErrorCount})
+		paused, err := s.st.IsFeedPaused(cf.Name)
+		if err != nil {
+			return err
+		}
+		feedsTable.AddRow([]any{o.Name, !cf.Disabled, paused, o.Health(), o.NextPoll, cf.Webhooks, o.ReceivedCount, o.ReceivedLast, o.ErrorCount})
 	}
 	feedsTable.Print()
 	fmt.Fprintln(out)
 	// Webhook stats
-	whTable := consoletable.New("Webhooks", 5)
+	whTable := consoletable.New("Webhooks", 6)
 	whTable.Target = out
-	whTable.AddRow([]any{"Name", "Queued", "Sent", "Last", "Errors"})
+	whTable.AddRow([]any{"Name", "Paused", "Queued", "Sent", "Last", "Errors"})
 	slices.SortFunc(s.cfg.Webhooks, func(a, b config.ConfigWebhook) int {
 		return cmp.Compare(a.Name, b.Name)
 	})
@@ -96,7 +160,11 @@ func (s *RemoteService) Statistics(args *EmptyArgs, reply *string) error {
 		if err != nil {
 			slog.Error("Failed to fetch queue size for webhook", "webhook", cw.Name)
 		}
-		whTable.AddRow([]any{o.Name, ms.QueueSize, o.SentCount, o.SentLast, ms.ErrorCount})
+		paused, err := s.st.IsWebhookPaused(cw.Name)
+		if err != nil {
+			return err
+		}
+		whTable.AddRow([]any{o.Name, paused, ms.QueueSize, o.SentCount, o.SentLast, ms.ErrorCount})
 	}
 	whTable.Print()
 	*reply = out.String()
@@ -114,7 +182,18 @@ func (s *RemoteService) SendPing(args *SendPingArgs, reply *bool) error {
 	if wh.Name == "" {
 		return fmt.Errorf("no webhook found with the name %s", args.WebhookName)
 	}
-	dh := s.client.NewWebhook(wh.URL)
-	_, err := dh.Execute(dhook.Message{Content: "Ping from feedhook"}, nil)
-	return err
+	u, m := pingMessage(wh)
+	dh := s.d.WebhookClient(wh.Name).NewWebhook(u)
+	return messenger.ExecuteOrRecord(s.cfg, dh, wh.Name, m)
+}
+
+// pingMessage returns the URL and message for pinging a webhook.
+// Pings are posted into the configured thread or as new post into a forum.
+func pingMessage(wh config.ConfigWebhook) (string, dhook.Message) {
+	m := dhook.Message{Content: "Ping from feedhook"}
+	if wh.Forum && wh.ThreadID == "" {
+		m.ThreadName = "Ping from feedhook"
+		m.AppliedTags = wh.ForumTags
+	}
+	return messenger.WebhookURL(wh.URL, wh.ThreadID, false), m
 }
@@ -0,0 +1,29 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+)
+
+func TestPingMessage(t *testing.T) {
+	t.Run("should ping normal webhook", func(t *testing.T) {
+		u, m := pingMessage(config.ConfigWebhook{URL: "https://discord.com/api/webhooks/1/abc"})
+		assert.Equal(t, "https://discord.com/api/webhooks/1/abc", u)
+		assert.Equal(t, "Ping from feedhook", m.Content)
+		assert.Equal(t, "", m.ThreadName)
+	})
+	t.Run("should ping into thread", func(t *testing.T) {
+		u, m := pingMessage(config.ConfigWebhook{URL: "https://discord.com/api/webhooks/1/abc", ThreadID: "123"})
+		assert.Equal(t, "https://discord.com/api/webhooks/1/abc?thread_id=123", u)
+		assert.Equal(t, "", m.ThreadName)
+	})
+	t.Run("should create forum post for ping", func(t *testing.T) {
+		u, m := pingMessage(config.ConfigWebhook{URL: "https://discord.com/api/webhooks/1/abc", Forum: true, ForumTags: []string{"1"}})
+		assert.Equal(t, "https://discord.com/api/webhooks/1/abc", u)
+		assert.Equal(t, "Ping from feedhook", m.ThreadName)
+		assert.Equal(t, []string{"1"}, m.AppliedTags)
+	})
+}
@@ -1,12 +1,25 @@
 package app
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type FeedStats struct {
-	Name          string
-	ErrorCount    int
-	ReceivedCount int
-	ReceivedLast  time.Time
+	Name              string
+	ConsecutiveErrors int
+	ErrorCount        int
+	NextPoll          time.Time // zero when the feed is polled with every tick
+	ReceivedCount     int
+	ReceivedLast      time.Time
+}
+
+// Health returns a description of the current health of a feed.
+func (fs FeedStats) Health() string {
+	if fs.ConsecutiveErrors == 0 {
+		return "ok"
+	}
+	return fmt.Sprintf("failing (%d)", fs.ConsecutiveErrors)
 }
 
 type WebhookStats struct {
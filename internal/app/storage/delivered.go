@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	"github.com/ErikKalkoken/feedhook/internal/app"
+	bolt "go.etcd.io/bbolt"
+)
+
+// IsDuplicate reports wether an item identified by key was already delivered to a webhook
+// from another feed within the time window.
+func (st *Storage) IsDuplicate(webhookName, key, feedName string, now time.Time, window time.Duration) (bool, error) {
+	var isDuplicate bool
+	err := st.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketDelivered))
+		b := root.Bucket([]byte(webhookName))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+		var di app.DeliveredItem
+		if err := gob.NewDecoder(bytes.NewBuffer(v)).Decode(&di); err != nil {
+			return err
+		}
+		isDuplicate = di.FeedName != feedName && now.Sub(di.DeliveredAt) < window
+		return nil
+	})
+	return isDuplicate, err
+}
+
+// MarkDelivered records that an item identified by key is delivered from a feed to a webhook.
+func (st *Storage) MarkDelivered(webhookName, key, feedName string, now time.Time) error {
+	err := st.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketDelivered))
+		b, err := root.CreateBucketIfNotExists([]byte(webhookName))
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(app.DeliveredItem{FeedName: feedName, DeliveredAt: now}); err != nil {
+			return err
+		}
+		return b.Put([]byte(key), buf.Bytes())
+	})
+	return err
+}
+
+// CullDelivered deletes all records of delivered items for a webhook, which are older then a time.
+func (st *Storage) CullDelivered(webhookName string, before time.Time) error {
+	err := st.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketDelivered))
+		b := root.Bucket([]byte(webhookName))
+		if b == nil {
+			return nil
+		}
+		keys := make([][]byte, 0)
+		err := b.ForEach(func(k, v []byte) error {
+			var di app.DeliveredItem
+			if err := gob.NewDecoder(bytes.NewBuffer(v)).Decode(&di); err != nil {
+				return err
+			}
+			if di.DeliveredAt.Before(before) {
+				keys = append(keys, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,129 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+)
+
+func TestDelivered(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	st := storage.New(db, config.Config{})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+	window := time.Hour
+	t.Run("should report duplicate from another feed within window", func(t *testing.T) {
+		if err := st.MarkDelivered("hook1", "key1", "feed1", now); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := st.IsDuplicate("hook1", "key1", "feed2", now.Add(30*time.Minute), window)
+		if assert.NoError(t, err) {
+			assert.True(t, ok)
+		}
+	})
+	t.Run("should not report duplicate from same feed", func(t *testing.T) {
+		if err := st.MarkDelivered("hook1", "key2", "feed1", now); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := st.IsDuplicate("hook1", "key2", "feed1", now, window)
+		if assert.NoError(t, err) {
+			assert.False(t, ok)
+		}
+	})
+	t.Run("should not report duplicate after window", func(t *testing.T) {
+		if err := st.MarkDelivered("hook1", "key3", "feed1", now); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := st.IsDuplicate("hook1", "key3", "feed2", now.Add(2*time.Hour), window)
+		if assert.NoError(t, err) {
+			assert.False(t, ok)
+		}
+	})
+	t.Run("should not report duplicate for other webhook", func(t *testing.T) {
+		if err := st.MarkDelivered("hook1", "key4", "feed1", now); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := st.IsDuplicate("hook2", "key4", "feed2", now, window)
+		if assert.NoError(t, err) {
+			assert.False(t, ok)
+		}
+	})
+	t.Run("should not report duplicate for items not marked as delivered", func(t *testing.T) {
+		ok, err := st.IsDuplicate("hook1", "key7", "feed1", now, window)
+		if assert.NoError(t, err) {
+			assert.False(t, ok)
+		}
+		ok, err = st.IsDuplicate("hook1", "key7", "feed2", now, window)
+		if assert.NoError(t, err) {
+			assert.False(t, ok)
+		}
+	})
+	t.Run("can cull old records", func(t *testing.T) {
+		if err := st.MarkDelivered("hook3", "key5", "feed1", now.Add(-2*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.MarkDelivered("hook3", "key6", "feed1", now); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.CullDelivered("hook3", now.Add(-window)); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := st.IsDuplicate("hook3", "key5", "feed2", now, 24*time.Hour)
+		if assert.NoError(t, err) {
+			assert.False(t, ok)
+		}
+		ok, err = st.IsDuplicate("hook3", "key6", "feed2", now, window)
+		if assert.NoError(t, err) {
+			assert.True(t, ok)
+		}
+	})
+	t.Run("should ignore culling unknown webhook", func(t *testing.T) {
+		assert.NoError(t, st.CullDelivered("unknown", now))
+	})
+}
+
+func TestDeliveredCleanup(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+	cfg := config.Config{Webhooks: []config.ConfigWebhook{{Name: "hook1"}, {Name: "hook2"}}}
+	st := storage.New(db, cfg)
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	for _, name := range []string{"hook1", "hook2"} {
+		if err := st.MarkDelivered(name, "key1", "feed1", now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg.Webhooks = cfg.Webhooks[:1]
+	st = storage.New(db, cfg)
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	ok, err := st.IsDuplicate("hook1", "key1", "feed2", now, time.Hour)
+	if assert.NoError(t, err) {
+		assert.True(t, ok)
+	}
+	ok, err = st.IsDuplicate("hook2", "key1", "feed2", now, time.Hour)
+	if assert.NoError(t, err) {
+		assert.False(t, ok)
+	}
+}
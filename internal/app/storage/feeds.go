@@ -17,16 +17,25 @@ func (st *Storage) ListFeeds() ([]string, error) {
 
 func (st *Storage) ClearFeeds() error {
 	err := st.db.Update(func(tx *bolt.Tx) error {
-		root := tx.Bucket([]byte(bucketFeeds))
-		return root.ForEachBucket(func(k []byte) error {
-			b := root.Bucket(k)
-			return b.ForEach(func(k, v []byte) error {
-				if err := b.Delete(k); err != nil {
+		for _, n := range []string{bucketFeeds, bucketItemIndex, bucketTranslations} {
+			root := tx.Bucket([]byte(n))
+			err := root.ForEachBucket(func(k []byte) error {
+				b := root.Bucket(k)
+				if err := b.SetSequence(0); err != nil {
 					return err
 				}
-				return nil
+				return b.ForEach(func(k, v []byte) error {
+					if err := b.Delete(k); err != nil {
+						return err
+					}
+					return nil
+				})
 			})
-		})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	return err
 }
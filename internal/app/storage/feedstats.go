@@ -36,6 +36,20 @@ func (st *Storage) UpdateFeedStats(name string, f func(fs *app.FeedStats) error)
 	return err
 }
 
+func (st *Storage) ClearFeedStats() error {
+	err := st.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketStats))
+		b := root.Bucket([]byte(bucketFeeds))
+		return b.ForEach(func(k, v []byte) error {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			return nil
+		})
+	})
+	return err
+}
+
 // GetFeedStats returns the stats for a feed.
 func (st *Storage) GetFeedStats(name string) (*app.FeedStats, error) {
 	fs := &app.FeedStats{Name: name}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"slices"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ErikKalkoken/feedhook/internal/app"
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/urls"
+)
+
+// itemIdentity defines how the items of a feed are identified.
+type itemIdentity struct {
+	Key    string
+	Fields []string // fields for hash key
+}
+
+var defaultItemIdentity = itemIdentity{Key: config.ItemIDGUID}
+
+func itemIdentityFromConfig(cf config.ConfigFeed) itemIdentity {
+	switch cf.ItemID {
+	case "":
+		return defaultItemIdentity
+	case config.ItemIDHash:
+		return itemIdentity{Key: cf.ItemID, Fields: cf.ItemIDFields}
+	}
+	return itemIdentity{Key: cf.ItemID}
+}
+
+func (x itemIdentity) equal(other itemIdentity) bool {
+	return x.Key == other.Key && slices.Equal(x.Fields, other.Fields)
+}
+
+// itemID returns the unique ID for a feed item.
+// Falls back to the default identity when the key field of an item is empty.
+func (x itemIdentity) itemID(item *gofeed.Item) string {
+	switch x.Key {
+	case config.ItemIDHash:
+		values := make([]string, 0, len(x.Fields))
+		for _, f := range x.Fields {
+			values = append(values, itemField(item, f))
+		}
+		return makeHash(strings.Join(values, "\x00"))
+	case config.ItemIDLink:
+		if item.Link != "" {
+			return item.Link
+		}
+	case config.ItemIDNormalizedLink:
+		if item.Link != "" {
+			return urls.Normalize(item.Link)
+		}
+	case config.ItemIDTitle:
+		if item.Title != "" {
+			return item.Title
+		}
+	}
+	return itemUniqueID(item)
+}
+
+func itemField(item *gofeed.Item, name string) string {
+	switch name {
+	case "content":
+		return item.Content
+	case "description":
+		return item.Description
+	case "guid":
+		return item.GUID
+	case "link":
+		return item.Link
+	case "published":
+		return item.Published
+	case "title":
+		return item.Title
+	}
+	return ""
+}
+
+// itemUniqueID returns the default unique ID for a feed item.
+// This is the GUID when provided or otherwise a hash of the item's content.
+func itemUniqueID(item *gofeed.Item) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	s := item.Title + item.Description + item.Content
+	return makeHash(s)
+}
+
+// feedItemIdentities are the recorded item identities of a feed.
+type feedItemIdentities struct {
+	Current  itemIdentity
+	Previous *itemIdentity // identity before the last change. Nil if never changed.
+}
+
+// updateItemIdentity records the current item identity of a feed
+// and returns the previous identity, when it has changed.
+// Feeds with items recorded before identities were stored had the default identity.
+func updateItemIdentity(tx *bolt.Tx, cf config.ConfigFeed) (*itemIdentity, error) {
+	b := tx.Bucket([]byte(bucketItemIDs))
+	current := itemIdentityFromConfig(cf)
+	var x feedItemIdentities
+	if v := b.Get([]byte(cf.Name)); v != nil {
+		if err := gob.NewDecoder(bytes.NewBuffer(v)).Decode(&x); err != nil {
+			return nil, err
+		}
+		if !x.Current.equal(current) {
+			previous := x.Current
+			x.Previous = &previous
+		}
+	} else if k, _ := tx.Bucket([]byte(bucketFeeds)).Bucket([]byte(cf.Name)).Cursor().First(); k != nil {
+		if !defaultItemIdentity.equal(current) {
+			x.Previous = &defaultItemIdentity
+		}
+	}
+	if x.Previous != nil && x.Previous.equal(current) {
+		x.Previous = nil
+	}
+	x.Current = current
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(x); err != nil {
+		return nil, err
+	}
+	if err := b.Put([]byte(cf.Name), buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return x.Previous, nil
+}
+
+// MigrateItemIDs migrates recorded items of a feed to the current item identity,
+// after the identity was changed. Only items contained in the given feed items are migrated,
+// since other items will not be received again. Translations of items are migrated too.
+// Returns the number of migrated items.
+func (st *Storage) MigrateItemIDs(cf config.ConfigFeed, items []*gofeed.Item) (int, error) {
+	previous, ok := st.previousItemIDs[cf.Name]
+	if !ok {
+		return 0, nil
+	}
+	current := itemIdentityFromConfig(cf)
+	// find items to migrate first, so that feeds without such items need no write transaction
+	type migration struct {
+		oldID, newID string
+	}
+	var migrations []migration
+	err := st.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketFeeds)).Bucket([]byte(cf.Name))
+		for _, item := range items {
+			newID, oldID := current.itemID(item), previous.itemID(item)
+			if newID == oldID || b.Get([]byte(newID)) != nil || b.Get([]byte(oldID)) == nil {
+				continue
+			}
+			migrations = append(migrations, migration{oldID: oldID, newID: newID})
+		}
+		return nil
+	})
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	var n int
+	err = st.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketFeeds)).Bucket([]byte(cf.Name))
+		idx := tx.Bucket([]byte(bucketItemIndex)).Bucket([]byte(cf.Name))
+		translations := tx.Bucket([]byte(bucketTranslations)).Bucket([]byte(cf.Name))
+		for _, m := range migrations {
+			v := b.Get([]byte(m.oldID))
+			if v == nil {
+				continue
+			}
+			i, err := app.NewProcessedItemFromBytes(v)
+			if err != nil {
+				return err
+			}
+			if err := idx.Delete(itemIndexKey(i)); err != nil {
+				return err
+			}
+			if err := b.Delete(i.Key()); err != nil {
+				return err
+			}
+			i.ID = m.newID
+			v, err = i.ToBytes()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(i.Key(), v); err != nil {
+				return err
+			}
+			if err := idx.Put(itemIndexKey(i), []byte{}); err != nil {
+				return err
+			}
+			if translations != nil {
+				if v := translations.Get([]byte(m.oldID)); v != nil {
+					if err := translations.Put([]byte(m.newID), bytes.Clone(v)); err != nil {
+						return err
+					}
+					if err := translations.Delete([]byte(m.oldID)); err != nil {
+						return err
+					}
+				}
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,203 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ErikKalkoken/feedhook/internal/app"
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+)
+
+func TestItemIdentity(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	cfLink := config.ConfigFeed{Name: "feed1", ItemID: config.ItemIDLink}
+	cfNormalized := config.ConfigFeed{Name: "feed2", ItemID: config.ItemIDNormalizedLink}
+	cfTitle := config.ConfigFeed{Name: "feed3", ItemID: config.ItemIDTitle}
+	cfHash := config.ConfigFeed{Name: "feed4", ItemID: config.ItemIDHash, ItemIDFields: []string{"title", "link"}}
+	st := storage.New(db, config.Config{Feeds: []config.ConfigFeed{cfLink, cfNormalized, cfTitle, cfHash}})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	cases := []struct {
+		name  string
+		cf    config.ConfigFeed
+		item1 *gofeed.Item
+		item2 *gofeed.Item
+		want  app.ItemState
+	}{
+		{
+			"should identify items by link",
+			cfLink,
+			&gofeed.Item{GUID: "1", Link: "https://www.example.com/a"},
+			&gofeed.Item{GUID: "2", Link: "https://www.example.com/a"},
+			app.StateProcessed,
+		},
+		{
+			"should identify items by normalized link",
+			cfNormalized,
+			&gofeed.Item{GUID: "1", Link: "https://www.example.com/a?utm_source=rss"},
+			&gofeed.Item{GUID: "2", Link: "https://www.example.com/a"},
+			app.StateProcessed,
+		},
+		{
+			"should identify items by title",
+			cfTitle,
+			&gofeed.Item{GUID: "1", Title: "Alpha", Content: "first"},
+			&gofeed.Item{GUID: "2", Title: "Alpha", Content: "second"},
+			app.StateProcessed,
+		},
+		{
+			"should identify items by hash of selected fields",
+			cfHash,
+			&gofeed.Item{GUID: "1", Title: "Alpha", Link: "https://www.example.com/a", Content: "first"},
+			&gofeed.Item{GUID: "2", Title: "Alpha", Link: "https://www.example.com/a", Content: "second"},
+			app.StateProcessed,
+		},
+		{
+			"should report new item when selected fields differ",
+			cfHash,
+			&gofeed.Item{Title: "Alpha", Link: "https://www.example.com/a"},
+			&gofeed.Item{Title: "Bravo", Link: "https://www.example.com/a"},
+			app.StateNew,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := st.ClearFeeds(); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.RecordItem(tc.cf, tc.item1, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			s, err := st.GetItemState(tc.cf, tc.item2, time.Now())
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, s)
+			}
+		})
+	}
+}
+
+func TestMigrateItemIDs(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	cf := config.ConfigFeed{Name: "feed1"}
+	st := storage.New(db, config.Config{Feeds: []config.ConfigFeed{cf}})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	published := time.Now().UTC()
+	item1 := &gofeed.Item{GUID: "1", Link: "https://www.example.com/a", PublishedParsed: &published}
+	item2 := &gofeed.Item{GUID: "2", Link: "https://www.example.com/b", PublishedParsed: &published}
+	for _, i := range []*gofeed.Item{item1, item2} {
+		if err := st.RecordItem(cf, i, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// switch identity to link
+	cf.ItemID = config.ItemIDLink
+	st = storage.New(db, config.Config{Feeds: []config.ConfigFeed{cf}})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	t.Run("should migrate items to new identity", func(t *testing.T) {
+		n, err := st.MigrateItemIDs(cf, []*gofeed.Item{item1})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, n)
+		}
+		s, err := st.GetItemState(cf, &gofeed.Item{GUID: "rotated", Link: item1.Link, PublishedParsed: &published}, time.Now())
+		if assert.NoError(t, err) {
+			assert.Equal(t, app.StateProcessed, s)
+		}
+		assert.Equal(t, 2, st.ItemCount(cf))
+	})
+	t.Run("should keep migrating after restart", func(t *testing.T) {
+		st := storage.New(db, config.Config{Feeds: []config.ConfigFeed{cf}})
+		if err := st.Init(); err != nil {
+			t.Fatalf("Failed to init: %s", err)
+		}
+		n, err := st.MigrateItemIDs(cf, []*gofeed.Item{item1, item2})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, n)
+		}
+		s, err := st.GetItemState(cf, item2, time.Now())
+		if assert.NoError(t, err) {
+			assert.Equal(t, app.StateProcessed, s)
+		}
+	})
+	t.Run("should not write when there is nothing to migrate", func(t *testing.T) {
+		stats := db.Stats()
+		before := stats.TxStats.GetWrite()
+		n, err := st.MigrateItemIDs(cf, []*gofeed.Item{item1, item2})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, n)
+		}
+		stats = db.Stats()
+		assert.Equal(t, before, stats.TxStats.GetWrite())
+	})
+	t.Run("should not migrate feeds with unchanged identity", func(t *testing.T) {
+		other := config.ConfigFeed{Name: "feed2"}
+		st := storage.New(db, config.Config{Feeds: []config.ConfigFeed{cf, other}})
+		if err := st.Init(); err != nil {
+			t.Fatalf("Failed to init: %s", err)
+		}
+		n, err := st.MigrateItemIDs(other, []*gofeed.Item{item1})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, n)
+		}
+	})
+}
+
+func TestMigrateItemIDsWithTranslations(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	cf := config.ConfigFeed{Name: "feed1"}
+	st := storage.New(db, config.Config{Feeds: []config.ConfigFeed{cf}})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	published := time.Now().UTC()
+	item := &gofeed.Item{GUID: "1", Title: "Hallo", Link: "https://www.example.com/a", PublishedParsed: &published}
+	if err := st.RecordItem(cf, item, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveTranslation(cf, item, "en", "Hello", ""); err != nil {
+		t.Fatal(err)
+	}
+	cf.ItemID = config.ItemIDLink
+	st = storage.New(db, config.Config{Feeds: []config.ConfigFeed{cf}})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	n, err := st.MigrateItemIDs(cf, []*gofeed.Item{item})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, n)
+	}
+	x, err := st.GetTranslation(cf, item, "en")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Hello", x.Title)
+	}
+	_, err = st.CullItems(cf, 0, published.Add(time.Second), nil)
+	if assert.NoError(t, err) {
+		_, err := st.GetTranslation(cf, item, "en")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	}
+}
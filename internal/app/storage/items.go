@@ -1,9 +1,10 @@
 package storage
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
-	"slices"
 	"time"
 
 	"github.com/ErikKalkoken/feedhook/internal/app"
@@ -12,78 +13,171 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
-func (st *Storage) RecordItem(cf config.ConfigFeed, item *gofeed.Item) error {
+// cullBatchSize is the max number of items deleted by one call of CullItems.
+const cullBatchSize = 500
+
+func (st *Storage) RecordItem(cf config.ConfigFeed, item *gofeed.Item, now time.Time) error {
 	err := st.db.Update(func(tx *bolt.Tx) error {
 		root := tx.Bucket([]byte(bucketFeeds))
 		b := root.Bucket([]byte(cf.Name))
-		i := processedItemFromFeed(item)
+		idx := tx.Bucket([]byte(bucketItemIndex)).Bucket([]byte(cf.Name))
+		i := processedItemFromFeed(cf, item, now)
+		if v := b.Get(i.Key()); v != nil {
+			old, err := app.NewProcessedItemFromBytes(v)
+			if err != nil {
+				return err
+			}
+			if err := idx.Delete(itemIndexKey(old)); err != nil {
+				return err
+			}
+		} else if err := idx.SetSequence(idx.Sequence() + 1); err != nil {
+			return err
+		}
 		v, err := i.ToBytes()
 		if err != nil {
 			return err
 		}
-		return b.Put(i.Key(), v)
+		if err := b.Put(i.Key(), v); err != nil {
+			return err
+		}
+		return idx.Put(itemIndexKey(i), []byte{})
 	})
 	return err
 }
 
 // GetItemState return the state of an item.
-func (st *Storage) GetItemState(cf config.ConfigFeed, item *gofeed.Item) (app.ItemState, error) {
+//
+// Wether a known item is reported as updated depends on the update policy of the feed.
+// Updates within the update interval of a feed are not reported,
+// so that they are combined into one notification once the interval has passed.
+// Items recorded without the data needed for a policy are reported as processed
+// and the data is recorded, so that later changes can be detected.
+func (st *Storage) GetItemState(cf config.ConfigFeed, item *gofeed.Item, now time.Time) (app.ItemState, error) {
 	var s app.ItemState
+	var baseline *app.ProcessedItem
 	err := st.db.View(func(tx *bolt.Tx) error {
 		root := tx.Bucket([]byte(bucketFeeds))
 		b := root.Bucket([]byte(cf.Name))
-		k := processedItemFromFeed(item).Key()
-		v := b.Get(k)
+		current := processedItemFromFeed(cf, item, now)
+		v := b.Get(current.Key())
 		if v == nil {
 			s = app.StateNew
 			return nil
 		}
-		if item.PublishedParsed == nil {
-			s = app.StateProcessed
-			return nil
-		}
 		pi, err := app.NewProcessedItemFromBytes(v)
 		if err != nil {
 			return err
 		}
-		if pp := *item.PublishedParsed; pp.Equal(pi.Published) {
+		var isUpdated bool
+		switch cf.UpdatePolicy {
+		case config.UpdatePolicyNone:
+		case config.UpdatePolicyContentHash:
+			if pi.ContentHash == "" {
+				pi.ContentHash = current.ContentHash
+				baseline = pi
+			} else {
+				isUpdated = pi.ContentHash != current.ContentHash
+			}
+		case config.UpdatePolicyUpdatedDate:
+			if item.UpdatedParsed == nil {
+				break
+			}
+			if pi.Updated.IsZero() {
+				pi.Updated = current.Updated
+				baseline = pi
+			} else {
+				isUpdated = !current.Updated.Equal(pi.Updated)
+			}
+		default:
+			isUpdated = item.PublishedParsed != nil && !item.PublishedParsed.Equal(pi.Published)
+		}
+		interval := time.Duration(cf.UpdateInterval) * time.Second
+		if isUpdated && (pi.Recorded.IsZero() || now.Sub(pi.Recorded) >= interval) {
+			s = app.StateUpdated
+		} else {
 			s = app.StateProcessed
-			return nil
 		}
-		s = app.StateUpdated
 		return nil
 	})
-	return s, err
+	if err != nil {
+		return 0, err
+	}
+	if baseline != nil {
+		if err := st.saveProcessedItem(cf, baseline); err != nil {
+			return 0, err
+		}
+	}
+	return s, nil
 }
 
-// CullItems deletes the oldest items when there are more items then a limit
-func (st *Storage) CullItems(cf config.ConfigFeed, limit int) error {
+// saveProcessedItem updates a processed item without changing it's publishing date.
+func (st *Storage) saveProcessedItem(cf config.ConfigFeed, pi *app.ProcessedItem) error {
+	err := st.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketFeeds)).Bucket([]byte(cf.Name))
+		v, err := pi.ToBytes()
+		if err != nil {
+			return err
+		}
+		return b.Put(pi.Key(), v)
+	})
+	return err
+}
+
+// CullItems deletes the oldest items of a feed when there are more items then maxItems
+// and all items published before a time. Zero values disable the respective limit.
+// Items which are still in the current feed are not deleted for their age,
+// because they would otherwise be reported as new again.
+//
+// Culling is incremental: Each call deletes at most cullBatchSize items
+// and reports wether there are more items to delete.
+func (st *Storage) CullItems(cf config.ConfigFeed, maxItems int, before time.Time, current []*gofeed.Item) (bool, error) {
+	keep := make(map[string]bool)
+	for _, item := range current {
+		keep[itemIdentityFromConfig(cf).itemID(item)] = true
+	}
+	var hasMore bool
 	err := st.db.Update(func(tx *bolt.Tx) error {
 		root := tx.Bucket([]byte(bucketFeeds))
 		b := root.Bucket([]byte(cf.Name))
-		items := make([]*app.ProcessedItem, 0)
-		b.ForEach(func(k, v []byte) error {
-			i, err := app.NewProcessedItemFromBytes(v)
-			if err != nil {
+		idx := tx.Bucket([]byte(bucketItemIndex)).Bucket([]byte(cf.Name))
+		translations := tx.Bucket([]byte(bucketTranslations)).Bucket([]byte(cf.Name))
+		n := int(idx.Sequence())
+		c := idx.Cursor()
+		var deleted int
+		for k, _ := c.First(); k != nil; {
+			published, id := parseItemIndexKey(k)
+			isTooMany := maxItems > 0 && n > maxItems
+			isTooOld := !before.IsZero() && published.Before(before)
+			if !isTooMany && !isTooOld {
+				break
+			}
+			if !isTooMany && keep[string(id)] {
+				k, _ = c.Next()
+				continue
+			}
+			if deleted == cullBatchSize {
+				hasMore = true
+				break
+			}
+			k = bytes.Clone(k)
+			if err := b.Delete(id); err != nil {
 				return err
 			}
-			items = append(items, i)
-			return nil
-		})
-		slices.SortFunc(items, func(a *app.ProcessedItem, b *app.ProcessedItem) int {
-			return a.Published.Compare(b.Published) * -1
-		})
-		if len(items) <= limit {
-			return nil
-		}
-		for _, i := range items[limit:] {
-			if err := b.Delete(i.Key()); err != nil {
+			if translations != nil {
+				if err := translations.Delete(id); err != nil {
+					return err
+				}
+			}
+			if err := c.Delete(); err != nil {
 				return err
 			}
+			n--
+			deleted++
+			k, _ = c.Seek(k)
 		}
-		return nil
+		return idx.SetSequence(uint64(n))
 	})
-	return err
+	return hasMore, err
 }
 
 func (st *Storage) ListItems(feed string) ([]*app.ProcessedItem, error) {
@@ -118,24 +212,57 @@ func (st *Storage) ItemCount(cf config.ConfigFeed) int {
 	return c
 }
 
-func processedItemFromFeed(item *gofeed.Item) *app.ProcessedItem {
+// itemIndexKey returns the key of an item in the item index.
+// Keys are ordered by publishing date.
+func itemIndexKey(i *app.ProcessedItem) []byte {
+	k := make([]byte, 8, 8+len(i.ID))
+	binary.BigEndian.PutUint64(k, uint64(i.Published.Unix())^(1<<63))
+	return append(k, i.ID...)
+}
+
+func parseItemIndexKey(k []byte) (time.Time, []byte) {
+	published := time.Unix(int64(binary.BigEndian.Uint64(k[:8])^(1<<63)), 0)
+	return published, k[8:]
+}
+
+// buildItemIndex creates the item index for the items of a feed.
+func buildItemIndex(b *bolt.Bucket, root *bolt.Bucket, feedName string) error {
+	idx, err := root.CreateBucket([]byte(feedName))
+	if err != nil {
+		return err
+	}
+	var n uint64
+	err = b.ForEach(func(k, v []byte) error {
+		i, err := app.NewProcessedItemFromBytes(v)
+		if err != nil {
+			return err
+		}
+		n++
+		return idx.Put(itemIndexKey(i), []byte{})
+	})
+	if err != nil {
+		return err
+	}
+	return idx.SetSequence(n)
+}
+
+func processedItemFromFeed(cf config.ConfigFeed, item *gofeed.Item, now time.Time) *app.ProcessedItem {
 	var t time.Time
 	if item.PublishedParsed != nil {
 		t = *item.PublishedParsed
 	} else {
-		t = time.Now().UTC()
+		t = now.UTC()
 	}
-	return &app.ProcessedItem{ID: itemUniqueID(item), Published: t}
-}
-
-// itemUniqueID returns the unique ID for a feed item.
-// This is the GUID when provided or otherwise a hash of the item's content.
-func itemUniqueID(item *gofeed.Item) string {
-	if item.GUID != "" {
-		return item.GUID
+	pi := &app.ProcessedItem{
+		ID:          itemIdentityFromConfig(cf).itemID(item),
+		Published:   t,
+		ContentHash: makeHash(item.Title + item.Description + item.Content),
+		Recorded:    now.UTC(),
+	}
+	if item.UpdatedParsed != nil {
+		pi.Updated = *item.UpdatedParsed
 	}
-	s := item.Title + item.Description + item.Content
-	return makeHash(s)
+	return pi
 }
 
 func makeHash(s string) string {
@@ -1,6 +1,7 @@
 package storage_test
 
 import (
+	"fmt"
 	"path/filepath"
 	"testing"
 	"time"
@@ -34,7 +35,7 @@ func TestItems(t *testing.T) {
 			t.Fatal(err)
 		}
 		i := &gofeed.Item{GUID: "abc1"}
-		s, err := st.GetItemState(cf, i)
+		s, err := st.GetItemState(cf, i, time.Now())
 		if assert.NoError(t, err) {
 			assert.Equal(t, app.StateNew, s)
 		}
@@ -45,11 +46,11 @@ func TestItems(t *testing.T) {
 		}
 		t1 := time.Now()
 		i1 := &gofeed.Item{GUID: "abc2", PublishedParsed: &t1}
-		if err := st.RecordItem(cf, i1); err != nil {
+		if err := st.RecordItem(cf, i1, time.Now()); err != nil {
 			t.Fatal(err)
 		}
 		i2 := &gofeed.Item{GUID: "abc2", PublishedParsed: &t1}
-		s, err := st.GetItemState(cf, i2)
+		s, err := st.GetItemState(cf, i2, time.Now())
 		if assert.NoError(t, err) {
 			assert.Equal(t, app.StateProcessed, s)
 		}
@@ -60,12 +61,12 @@ func TestItems(t *testing.T) {
 		}
 		t1 := time.Now().Add(-5 * time.Second)
 		i1 := &gofeed.Item{GUID: "abc2", PublishedParsed: &t1}
-		if err := st.RecordItem(cf, i1); err != nil {
+		if err := st.RecordItem(cf, i1, time.Now()); err != nil {
 			t.Fatal(err)
 		}
 		t2 := time.Now()
 		i2 := &gofeed.Item{GUID: "abc2", PublishedParsed: &t2}
-		s, err := st.GetItemState(cf, i2)
+		s, err := st.GetItemState(cf, i2, time.Now())
 		if assert.NoError(t, err) {
 			assert.Equal(t, app.StateUpdated, s)
 		}
@@ -75,7 +76,7 @@ func TestItems(t *testing.T) {
 			t.Fatal(err)
 		}
 		i := &gofeed.Item{Title: "title", Description: "description"}
-		s, err := st.GetItemState(cf, i)
+		s, err := st.GetItemState(cf, i, time.Now())
 		if assert.NoError(t, err) {
 			assert.Equal(t, app.StateNew, s)
 		}
@@ -85,10 +86,10 @@ func TestItems(t *testing.T) {
 			t.Fatal(err)
 		}
 		i := &gofeed.Item{Title: "title", Description: "description"}
-		if err := st.RecordItem(cf, i); err != nil {
+		if err := st.RecordItem(cf, i, time.Now()); err != nil {
 			t.Fatal(err)
 		}
-		s, err := st.GetItemState(cf, i)
+		s, err := st.GetItemState(cf, i, time.Now())
 		if assert.NoError(t, err) {
 			assert.Equal(t, app.StateProcessed, s)
 		}
@@ -99,19 +100,20 @@ func TestItems(t *testing.T) {
 		}
 		now := time.Now().Add(-10 * time.Hour)
 		t1 := now.Add(5 * time.Hour)
-		if err := st.RecordItem(cf, &gofeed.Item{GUID: "1", PublishedParsed: &t1}); err != nil {
+		if err := st.RecordItem(cf, &gofeed.Item{GUID: "1", PublishedParsed: &t1}, time.Now()); err != nil {
 			t.Fatal(err)
 		}
 		t2 := now.Add(1 * time.Hour)
-		if err := st.RecordItem(cf, &gofeed.Item{GUID: "2", PublishedParsed: &t2}); err != nil {
+		if err := st.RecordItem(cf, &gofeed.Item{GUID: "2", PublishedParsed: &t2}, time.Now()); err != nil {
 			t.Fatal(err)
 		}
 		t3 := now.Add(4 * time.Hour)
-		if err := st.RecordItem(cf, &gofeed.Item{GUID: "3", PublishedParsed: &t3}); err != nil {
+		if err := st.RecordItem(cf, &gofeed.Item{GUID: "3", PublishedParsed: &t3}, time.Now()); err != nil {
 			t.Fatal(err)
 		}
-		err := st.CullItems(cf, 2)
+		hasMore, err := st.CullItems(cf, 2, time.Time{}, nil)
 		if assert.NoError(t, err) {
+			assert.False(t, hasMore)
 			assert.Equal(t, 2, st.ItemCount(cf))
 			ii, err := st.ListItems(cf.Name)
 			if assert.NoError(t, err) {
@@ -124,4 +126,268 @@ func TestItems(t *testing.T) {
 			}
 		}
 	})
+	t.Run("should delete items published before a time", func(t *testing.T) {
+		if err := st.ClearFeeds(); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now().UTC()
+		for i, d := range []time.Duration{-3 * time.Hour, -2 * time.Hour, -1 * time.Hour} {
+			t1 := now.Add(d)
+			if err := st.RecordItem(cf, &gofeed.Item{GUID: fmt.Sprint(i + 1), PublishedParsed: &t1}, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := st.CullItems(cf, 0, now.Add(-90*time.Minute), nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, st.ItemCount(cf))
+			ii, err := st.ListItems(cf.Name)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "3", ii[0].ID)
+			}
+		}
+	})
+	t.Run("should not delete old items which are still in the feed", func(t *testing.T) {
+		if err := st.ClearFeeds(); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now().UTC()
+		recorded := now.Add(-3 * time.Hour)
+		undated := &gofeed.Item{GUID: "1"}
+		if err := st.RecordItem(cf, undated, recorded); err != nil {
+			t.Fatal(err)
+		}
+		t2 := now.Add(-2 * time.Hour)
+		if err := st.RecordItem(cf, &gofeed.Item{GUID: "2", PublishedParsed: &t2}, now); err != nil {
+			t.Fatal(err)
+		}
+		_, err := st.CullItems(cf, 0, now.Add(-time.Hour), []*gofeed.Item{undated})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, st.ItemCount(cf))
+			ii, err := st.ListItems(cf.Name)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "1", ii[0].ID)
+			}
+			s, err := st.GetItemState(cf, undated, now)
+			if assert.NoError(t, err) {
+				assert.Equal(t, app.StateProcessed, s)
+			}
+		}
+	})
+	t.Run("should cull items incrementally", func(t *testing.T) {
+		if err := st.ClearFeeds(); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now().UTC()
+		for i := range 1200 {
+			t1 := now.Add(time.Duration(i) * time.Second)
+			if err := st.RecordItem(cf, &gofeed.Item{GUID: fmt.Sprint(i), PublishedParsed: &t1}, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hasMore, err := st.CullItems(cf, 100, time.Time{}, nil)
+		if assert.NoError(t, err) {
+			assert.True(t, hasMore)
+			assert.Equal(t, 700, st.ItemCount(cf))
+		}
+		hasMore, err = st.CullItems(cf, 100, time.Time{}, nil)
+		if assert.NoError(t, err) {
+			assert.True(t, hasMore)
+			assert.Equal(t, 200, st.ItemCount(cf))
+		}
+		hasMore, err = st.CullItems(cf, 100, time.Time{}, nil)
+		if assert.NoError(t, err) {
+			assert.False(t, hasMore)
+			assert.Equal(t, 100, st.ItemCount(cf))
+		}
+	})
+	t.Run("should not count updated items twice", func(t *testing.T) {
+		if err := st.ClearFeeds(); err != nil {
+			t.Fatal(err)
+		}
+		t1 := time.Now().Add(-time.Hour)
+		if err := st.RecordItem(cf, &gofeed.Item{GUID: "1", PublishedParsed: &t1}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		t2 := time.Now()
+		if err := st.RecordItem(cf, &gofeed.Item{GUID: "1", PublishedParsed: &t2}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.RecordItem(cf, &gofeed.Item{GUID: "2", PublishedParsed: &t2}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		_, err := st.CullItems(cf, 2, time.Time{}, nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 2, st.ItemCount(cf))
+		}
+	})
+}
+
+func TestItemIndexMigration(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	cf := config.ConfigFeed{Name: "feed1", URL: "https://www.example.com/feed", Webhooks: []string{"hook1"}}
+	st := storage.New(db, config.Config{Feeds: []config.ConfigFeed{cf}})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	now := time.Now().UTC()
+	for i := range 3 {
+		t1 := now.Add(time.Duration(i) * time.Hour)
+		if err := st.RecordItem(cf, &gofeed.Item{GUID: fmt.Sprint(i), PublishedParsed: &t1}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// simulate a database from before the item index existed
+	if err := db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte("itemindex"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	_, err = st.CullItems(cf, 1, time.Time{}, nil)
+	if assert.NoError(t, err) {
+		ii, err := st.ListItems(cf.Name)
+		if assert.NoError(t, err) && assert.Len(t, ii, 1) {
+			assert.Equal(t, "2", ii[0].ID)
+		}
+	}
+}
+
+func TestItemUpdatePolicy(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	cfPublished := config.ConfigFeed{Name: "feed1"}
+	cfUpdated := config.ConfigFeed{Name: "feed2", UpdatePolicy: config.UpdatePolicyUpdatedDate}
+	cfContent := config.ConfigFeed{Name: "feed3", UpdatePolicy: config.UpdatePolicyContentHash}
+	cfNone := config.ConfigFeed{Name: "feed4", UpdatePolicy: config.UpdatePolicyNone}
+	cfInterval := config.ConfigFeed{Name: "feed5", UpdatePolicy: config.UpdatePolicyContentHash, UpdateInterval: 3600}
+	st := storage.New(db, config.Config{Feeds: []config.ConfigFeed{cfPublished, cfUpdated, cfContent, cfNone, cfInterval}})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	t1 := time.Now().UTC().Add(-time.Hour)
+	t2 := time.Now().UTC()
+	cases := []struct {
+		name  string
+		cf    config.ConfigFeed
+		item1 *gofeed.Item
+		item2 *gofeed.Item
+		want  app.ItemState
+	}{
+		{
+			"should ignore changed updated date with published date policy",
+			cfPublished,
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, UpdatedParsed: &t1},
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, UpdatedParsed: &t2},
+			app.StateProcessed,
+		},
+		{
+			"should report changed updated date with updated date policy",
+			cfUpdated,
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, UpdatedParsed: &t1},
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, UpdatedParsed: &t2},
+			app.StateUpdated,
+		},
+		{
+			"should ignore changed published date with updated date policy",
+			cfUpdated,
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, UpdatedParsed: &t1},
+			&gofeed.Item{GUID: "1", PublishedParsed: &t2, UpdatedParsed: &t1},
+			app.StateProcessed,
+		},
+		{
+			"should report changed content with content hash policy",
+			cfContent,
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, Content: "alpha"},
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, Content: "bravo"},
+			app.StateUpdated,
+		},
+		{
+			"should ignore changed dates with content hash policy",
+			cfContent,
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, Content: "alpha"},
+			&gofeed.Item{GUID: "1", PublishedParsed: &t2, Content: "alpha"},
+			app.StateProcessed,
+		},
+		{
+			"should ignore all changes with none policy",
+			cfNone,
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, Content: "alpha"},
+			&gofeed.Item{GUID: "1", PublishedParsed: &t2, Content: "bravo"},
+			app.StateProcessed,
+		},
+		{
+			"should not report update within update interval",
+			cfInterval,
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, Content: "alpha"},
+			&gofeed.Item{GUID: "1", PublishedParsed: &t1, Content: "bravo"},
+			app.StateProcessed,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := st.ClearFeeds(); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.RecordItem(tc.cf, tc.item1, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			s, err := st.GetItemState(tc.cf, tc.item2, time.Now())
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, s)
+			}
+		})
+	}
+	t.Run("should report update once update interval has passed", func(t *testing.T) {
+		if err := st.ClearFeeds(); err != nil {
+			t.Fatal(err)
+		}
+		recorded := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+		if err := st.RecordItem(cfInterval, &gofeed.Item{GUID: "1", PublishedParsed: &t1, Content: "alpha"}, recorded); err != nil {
+			t.Fatal(err)
+		}
+		item := &gofeed.Item{GUID: "1", PublishedParsed: &t1, Content: "bravo"}
+		s, err := st.GetItemState(cfInterval, item, recorded.Add(30*time.Minute))
+		if assert.NoError(t, err) {
+			assert.Equal(t, app.StateProcessed, s)
+		}
+		s, err = st.GetItemState(cfInterval, item, recorded.Add(time.Hour))
+		if assert.NoError(t, err) {
+			assert.Equal(t, app.StateUpdated, s)
+		}
+	})
+	t.Run("should record missing content hash and report later changes", func(t *testing.T) {
+		if err := st.ClearFeeds(); err != nil {
+			t.Fatal(err)
+		}
+		// simulate an item recorded before content hashes were stored
+		pi := app.ProcessedItem{ID: "1", Published: t1}
+		v, err := pi.ToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("feeds")).Bucket([]byte(cfContent.Name)).Put(pi.Key(), v)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		s, err := st.GetItemState(cfContent, &gofeed.Item{GUID: "1", Content: "alpha"}, time.Now())
+		if assert.NoError(t, err) {
+			assert.Equal(t, app.StateProcessed, s)
+		}
+		s, err = st.GetItemState(cfContent, &gofeed.Item{GUID: "1", Content: "bravo"}, time.Now())
+		if assert.NoError(t, err) {
+			assert.Equal(t, app.StateUpdated, s)
+		}
+	})
 }
@@ -0,0 +1,46 @@
+package storage
+
+import bolt "go.etcd.io/bbolt"
+
+// SetFeedPaused records wether a feed is paused.
+func (st *Storage) SetFeedPaused(name string, paused bool) error {
+	return st.setPaused(bucketFeeds, name, paused)
+}
+
+// IsFeedPaused reports wether a feed is paused.
+func (st *Storage) IsFeedPaused(name string) (bool, error) {
+	return st.isPaused(bucketFeeds, name)
+}
+
+// SetWebhookPaused records wether a webhook is paused.
+func (st *Storage) SetWebhookPaused(name string, paused bool) error {
+	return st.setPaused(bucketWebhooks, name, paused)
+}
+
+// IsWebhookPaused reports wether a webhook is paused.
+func (st *Storage) IsWebhookPaused(name string) (bool, error) {
+	return st.isPaused(bucketWebhooks, name)
+}
+
+func (st *Storage) setPaused(bucket, name string, paused bool) error {
+	err := st.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketPaused))
+		b := root.Bucket([]byte(bucket))
+		if !paused {
+			return b.Delete([]byte(name))
+		}
+		return b.Put([]byte(name), []byte{1})
+	})
+	return err
+}
+
+func (st *Storage) isPaused(bucket, name string) (bool, error) {
+	var paused bool
+	err := st.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketPaused))
+		b := root.Bucket([]byte(bucket))
+		paused = b.Get([]byte(name)) != nil
+		return nil
+	})
+	return paused, err
+}
@@ -0,0 +1,113 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+)
+
+func TestPaused(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	st := storage.New(db, config.Config{})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	t.Run("should report feed as not paused by default", func(t *testing.T) {
+		got, err := st.IsFeedPaused("feed1")
+		if assert.NoError(t, err) {
+			assert.False(t, got)
+		}
+	})
+	t.Run("can pause and resume a feed", func(t *testing.T) {
+		if err := st.SetFeedPaused("feed1", true); err != nil {
+			t.Fatal(err)
+		}
+		got, err := st.IsFeedPaused("feed1")
+		if assert.NoError(t, err) {
+			assert.True(t, got)
+		}
+		if err := st.SetFeedPaused("feed1", false); err != nil {
+			t.Fatal(err)
+		}
+		got, err = st.IsFeedPaused("feed1")
+		if assert.NoError(t, err) {
+			assert.False(t, got)
+		}
+	})
+	t.Run("can pause a webhook", func(t *testing.T) {
+		if err := st.SetWebhookPaused("hook1", true); err != nil {
+			t.Fatal(err)
+		}
+		got, err := st.IsWebhookPaused("hook1")
+		if assert.NoError(t, err) {
+			assert.True(t, got)
+		}
+		got, err = st.IsFeedPaused("hook1")
+		if assert.NoError(t, err) {
+			assert.False(t, got)
+		}
+	})
+}
+
+func TestPausedCleanup(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	cfg := config.Config{
+		Feeds:    []config.ConfigFeed{{Name: "feed1"}, {Name: "feed2"}},
+		Webhooks: []config.ConfigWebhook{{Name: "hook1"}, {Name: "hook2"}},
+	}
+	st := storage.New(db, cfg)
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	for _, name := range []string{"feed1", "feed2"} {
+		if err := st.SetFeedPaused(name, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"hook1", "hook2"} {
+		if err := st.SetWebhookPaused(name, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg.Feeds = cfg.Feeds[:1]
+	cfg.Webhooks = cfg.Webhooks[:1]
+	st = storage.New(db, cfg)
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	t.Run("should keep paused state of configured feeds and webhooks", func(t *testing.T) {
+		got, err := st.IsFeedPaused("feed1")
+		if assert.NoError(t, err) {
+			assert.True(t, got)
+		}
+		got, err = st.IsWebhookPaused("hook1")
+		if assert.NoError(t, err) {
+			assert.True(t, got)
+		}
+	})
+	t.Run("should remove paused state of removed feeds and webhooks", func(t *testing.T) {
+		got, err := st.IsFeedPaused("feed2")
+		if assert.NoError(t, err) {
+			assert.False(t, got)
+		}
+		got, err = st.IsWebhookPaused("hook2")
+		if assert.NoError(t, err) {
+			assert.False(t, got)
+		}
+	})
+}
@@ -10,9 +10,15 @@ import (
 )
 
 const (
-	bucketFeeds    = "feeds"
-	bucketStats    = "stats"
-	bucketWebhooks = "webhooks"
+	bucketDelivered     = "delivered"
+	bucketFeeds         = "feeds"
+	bucketItemIndex     = "itemindex"
+	bucketItemIDs       = "itemids"
+	bucketPaused        = "paused"
+	bucketStats         = "stats"
+	bucketSubscriptions = "subscriptions"
+	bucketTranslations  = "translations"
+	bucketWebhooks      = "webhooks"
 )
 
 var ErrNotFound = errors.New("not found")
@@ -20,12 +26,15 @@ var ErrNotFound = errors.New("not found")
 type Storage struct {
 	db  *bolt.DB
 	cfg config.Config
+
+	previousItemIDs map[string]itemIdentity // feeds with a changed item identity
 }
 
 func New(db *bolt.DB, cfg config.Config) *Storage {
 	st := &Storage{
-		db:  db,
-		cfg: cfg,
+		db:              db,
+		cfg:             cfg,
+		previousItemIDs: make(map[string]itemIdentity),
 	}
 	return st
 }
@@ -36,6 +45,10 @@ func (st *Storage) Init() error {
 	for _, f := range st.cfg.Feeds {
 		feeds[f.Name] = true
 	}
+	webhooks := make(map[string]bool)
+	for _, w := range st.cfg.Webhooks {
+		webhooks[w.Name] = true
+	}
 	err := st.db.Update(func(tx *bolt.Tx) error {
 		// feeds bucket
 		bf, err := tx.CreateBucketIfNotExists([]byte(bucketFeeds))
@@ -58,6 +71,45 @@ func (st *Storage) Init() error {
 			}
 			return nil
 		})
+		// item index bucket
+		bi, err := tx.CreateBucketIfNotExists([]byte(bucketItemIndex))
+		if err != nil {
+			return err
+		}
+		for f := range feeds {
+			if bi.Bucket([]byte(f)) != nil {
+				continue
+			}
+			if err := buildItemIndex(bf.Bucket([]byte(f)), bi, f); err != nil {
+				return err
+			}
+		}
+		obsolete := make([][]byte, 0)
+		bi.ForEachBucket(func(k []byte) error {
+			if !feeds[string(k)] {
+				obsolete = append(obsolete, k)
+			}
+			return nil
+		})
+		for _, k := range obsolete {
+			if err := bi.DeleteBucket(k); err != nil {
+				return err
+			}
+		}
+		// item IDs bucket
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketItemIDs)); err != nil {
+			return err
+		}
+		for _, cf := range st.cfg.Feeds {
+			previous, err := updateItemIdentity(tx, cf)
+			if err != nil {
+				return err
+			}
+			if previous != nil {
+				st.previousItemIDs[cf.Name] = *previous
+				slog.Info("Item identity of feed has changed. Items are migrated when received.", "name", cf.Name, "previous", previous.Key, "current", itemIdentityFromConfig(cf).Key)
+			}
+		}
 		// stats bucket
 		bs, err := tx.CreateBucketIfNotExists([]byte(bucketStats))
 		if err != nil {
@@ -69,6 +121,67 @@ func (st *Storage) Init() error {
 		if _, err := bs.CreateBucketIfNotExists([]byte(bucketWebhooks)); err != nil {
 			return err
 		}
+		// paused bucket
+		bp, err := tx.CreateBucketIfNotExists([]byte(bucketPaused))
+		if err != nil {
+			return err
+		}
+		for bucket, names := range map[string]map[string]bool{bucketFeeds: feeds, bucketWebhooks: webhooks} {
+			b, err := bp.CreateBucketIfNotExists([]byte(bucket))
+			if err != nil {
+				return err
+			}
+			obsolete = obsolete[:0]
+			b.ForEach(func(k, v []byte) error {
+				if !names[string(k)] {
+					obsolete = append(obsolete, k)
+				}
+				return nil
+			})
+			for _, k := range obsolete {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		// delivered bucket
+		bd, err := tx.CreateBucketIfNotExists([]byte(bucketDelivered))
+		if err != nil {
+			return err
+		}
+		obsolete = obsolete[:0]
+		bd.ForEachBucket(func(k []byte) error {
+			if !webhooks[string(k)] {
+				obsolete = append(obsolete, k)
+			}
+			return nil
+		})
+		for _, k := range obsolete {
+			if err := bd.DeleteBucket(k); err != nil {
+				return err
+			}
+		}
+		// subscriptions bucket
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketSubscriptions)); err != nil {
+			return err
+		}
+		// translations bucket
+		bt, err := tx.CreateBucketIfNotExists([]byte(bucketTranslations))
+		if err != nil {
+			return err
+		}
+		obsolete = obsolete[:0]
+		bt.ForEachBucket(func(k []byte) error {
+			if !feeds[string(k)] {
+				obsolete = append(obsolete, k)
+			}
+			return nil
+		})
+		for _, k := range obsolete {
+			if err := bt.DeleteBucket(k); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 	return err
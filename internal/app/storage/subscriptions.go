@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/ErikKalkoken/feedhook/internal/app"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SaveSubscription creates or updates a WebSub subscription.
+func (st *Storage) SaveSubscription(s *app.Subscription) error {
+	err := st.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSubscriptions))
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(*s); err != nil {
+			return err
+		}
+		return b.Put([]byte(s.FeedName), buf.Bytes())
+	})
+	return err
+}
+
+// GetSubscription returns the WebSub subscription for a feed.
+// Returns ErrNotFound if the feed has no subscription.
+func (st *Storage) GetSubscription(feedName string) (*app.Subscription, error) {
+	var s app.Subscription
+	err := st.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSubscriptions))
+		v := b.Get([]byte(feedName))
+		if v == nil {
+			return ErrNotFound
+		}
+		return gob.NewDecoder(bytes.NewBuffer(v)).Decode(&s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteSubscription deletes the WebSub subscription for a feed.
+func (st *Storage) DeleteSubscription(feedName string) error {
+	err := st.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSubscriptions))
+		return b.Delete([]byte(feedName))
+	})
+	return err
+}
+
+// ListSubscriptions returns all WebSub subscriptions.
+func (st *Storage) ListSubscriptions() ([]*app.Subscription, error) {
+	var subs []*app.Subscription
+	err := st.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSubscriptions))
+		return b.ForEach(func(k, v []byte) error {
+			var s app.Subscription
+			if err := gob.NewDecoder(bytes.NewBuffer(v)).Decode(&s); err != nil {
+				return err
+			}
+			subs = append(subs, &s)
+			return nil
+		})
+	})
+	return subs, err
+}
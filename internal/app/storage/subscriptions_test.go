@@ -0,0 +1,60 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ErikKalkoken/feedhook/internal/app"
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+)
+
+func TestSubscriptions(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	st := storage.New(db, config.Config{})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	t.Run("can save and read a subscription", func(t *testing.T) {
+		now := time.Now().UTC()
+		s := &app.Subscription{FeedName: "feed1", Hub: "https://hub.example.com", RequestedAt: now}
+		if err := st.SaveSubscription(s); err != nil {
+			t.Fatal(err)
+		}
+		got, err := st.GetSubscription("feed1")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://hub.example.com", got.Hub)
+			assert.True(t, now.Equal(got.RequestedAt))
+		}
+	})
+	t.Run("should return not found when no subscription exists", func(t *testing.T) {
+		_, err := st.GetSubscription("unknown")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+	t.Run("can list subscriptions", func(t *testing.T) {
+		subs, err := st.ListSubscriptions()
+		if assert.NoError(t, err) && assert.Len(t, subs, 1) {
+			assert.Equal(t, "feed1", subs[0].FeedName)
+		}
+	})
+	t.Run("can delete a subscription", func(t *testing.T) {
+		s := &app.Subscription{FeedName: "feed2"}
+		if err := st.SaveSubscription(s); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.DeleteSubscription("feed2"); err != nil {
+			t.Fatal(err)
+		}
+		_, err := st.GetSubscription("feed2")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
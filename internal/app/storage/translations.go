@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/ErikKalkoken/feedhook/internal/app"
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/mmcdole/gofeed"
+	bolt "go.etcd.io/bbolt"
+)
+
+// GetTranslation returns the translation of an item into the target language.
+// Returns ErrNotFound when there is no translation or the item has changed since it was translated.
+func (st *Storage) GetTranslation(cf config.ConfigFeed, item *gofeed.Item, target string) (app.Translation, error) {
+	var t app.Translation
+	err := st.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketTranslations)).Bucket([]byte(cf.Name))
+		if b == nil {
+			return ErrNotFound
+		}
+		v := b.Get([]byte(itemIdentityFromConfig(cf).itemID(item)))
+		if v == nil {
+			return ErrNotFound
+		}
+		if err := gob.NewDecoder(bytes.NewBuffer(v)).Decode(&t); err != nil {
+			return err
+		}
+		if t.Target != target || t.SourceHash != translationSourceHash(item) {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return t, err
+}
+
+// SaveTranslation records the translation of an item into the target language.
+func (st *Storage) SaveTranslation(cf config.ConfigFeed, item *gofeed.Item, target, title, description string) error {
+	t := app.Translation{
+		Description: description,
+		SourceHash:  translationSourceHash(item),
+		Target:      target,
+		Title:       title,
+	}
+	err := st.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket([]byte(bucketTranslations)).CreateBucketIfNotExists([]byte(cf.Name))
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(t); err != nil {
+			return err
+		}
+		return b.Put([]byte(itemIdentityFromConfig(cf).itemID(item)), buf.Bytes())
+	})
+	return err
+}
+
+func translationSourceHash(item *gofeed.Item) string {
+	return makeHash(item.Title + item.Description + item.Content)
+}
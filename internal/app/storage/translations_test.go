@@ -0,0 +1,76 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+)
+
+func TestTranslations(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	cf := config.ConfigFeed{Name: "feed1"}
+	st := storage.New(db, config.Config{Feeds: []config.ConfigFeed{cf}})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	t.Run("should return saved translation", func(t *testing.T) {
+		item := &gofeed.Item{GUID: "id1", Title: "Hallo", Description: "Welt"}
+		if err := st.SaveTranslation(cf, item, "en", "Hello", "World"); err != nil {
+			t.Fatal(err)
+		}
+		x, err := st.GetTranslation(cf, item, "en")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Hello", x.Title)
+			assert.Equal(t, "World", x.Description)
+		}
+	})
+	t.Run("should return not found when there is no translation", func(t *testing.T) {
+		item := &gofeed.Item{GUID: "id2", Title: "Hallo"}
+		_, err := st.GetTranslation(cf, item, "en")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+	t.Run("should return not found for another target", func(t *testing.T) {
+		item := &gofeed.Item{GUID: "id3", Title: "Hallo"}
+		if err := st.SaveTranslation(cf, item, "en", "Hello", ""); err != nil {
+			t.Fatal(err)
+		}
+		_, err := st.GetTranslation(cf, item, "fr")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+	t.Run("should return not found when item has changed", func(t *testing.T) {
+		item := &gofeed.Item{GUID: "id4", Title: "Hallo"}
+		if err := st.SaveTranslation(cf, item, "en", "Hello", ""); err != nil {
+			t.Fatal(err)
+		}
+		item.Title = "Guten Tag"
+		_, err := st.GetTranslation(cf, item, "en")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+	t.Run("should delete translations of culled items", func(t *testing.T) {
+		published := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+		item := &gofeed.Item{GUID: "id5", Title: "Hallo", PublishedParsed: &published}
+		if err := st.RecordItem(cf, item, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.SaveTranslation(cf, item, "en", "Hello", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.CullItems(cf, 0, published.Add(time.Hour), nil); err != nil {
+			t.Fatal(err)
+		}
+		_, err := st.GetTranslation(cf, item, "en")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
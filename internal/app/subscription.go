@@ -0,0 +1,35 @@
+package app
+
+import "time"
+
+// Subscription represents a WebSub subscription of a feed at a hub.
+type Subscription struct {
+	FeedName    string
+	Hub         string
+	Topic       string
+	Secret      string
+	RequestedAt time.Time // when the subscription was last requested from the hub
+	VerifiedAt  time.Time // when the hub last verified the subscription. Zero if not yet verified.
+	ExpiresAt   time.Time // when the lease expires. Zero if not yet verified.
+}
+
+// IsActive reports wether the subscription is verified and not expired.
+func (s Subscription) IsActive(now time.Time) bool {
+	return !s.VerifiedAt.IsZero() && s.ExpiresAt.After(now)
+}
+
+// NeedsRenewal reports wether the subscription should be requested again.
+func (s Subscription) NeedsRenewal(now time.Time) bool {
+	return now.After(s.RenewalAt())
+}
+
+// RenewalAt returns when the subscription should be requested again.
+// This is when less then a tenth of the lease is left
+// or when the hub did not verify a requested subscription within a hour.
+func (s Subscription) RenewalAt() time.Time {
+	if s.VerifiedAt.IsZero() || s.RequestedAt.After(s.VerifiedAt) {
+		return s.RequestedAt.Add(time.Hour)
+	}
+	margin := s.ExpiresAt.Sub(s.VerifiedAt) / 10
+	return s.ExpiresAt.Add(-margin)
+}
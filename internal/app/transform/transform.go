@@ -0,0 +1,173 @@
+// Package transform provides transformers, which change feed items before they are posted.
+package transform
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/mmcdole/gofeed"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/urls"
+)
+
+// Transformer changes an item in place.
+type Transformer interface {
+	Transform(item *gofeed.Item) error
+}
+
+// Chain is an ordered list of transformers.
+type Chain []Transformer
+
+// New returns a new chain of transformers as defined by the config.
+func New(cts []config.ConfigTransformer) (Chain, error) {
+	var c Chain
+	for i, ct := range cts {
+		t, err := newTransformer(ct)
+		if err != nil {
+			return nil, fmt.Errorf("transform #%d: %w", i+1, err)
+		}
+		c = append(c, t)
+	}
+	return c, nil
+}
+
+func newTransformer(ct config.ConfigTransformer) (Transformer, error) {
+	switch ct.Type {
+	case config.TransformCategoryPrefix:
+		return categoryPrefix{field: fieldOrDefault(ct.Field, "title")}, nil
+	case config.TransformRegexReplace:
+		re, err := regexp.Compile(ct.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return regexReplace{field: fieldOrDefault(ct.Field, "title"), re: re, replace: ct.Replace}, nil
+	case config.TransformRewriteDomain:
+		return rewriteDomain{from: strings.ToLower(ct.From), to: ct.To}, nil
+	case config.TransformStripTracking:
+		return stripTracking{}, nil
+	case config.TransformTemplate:
+		t, err := template.New("").Parse(ct.Template)
+		if err != nil {
+			return nil, err
+		}
+		return templateField{field: fieldOrDefault(ct.Field, "title"), t: t}, nil
+	}
+	return nil, fmt.Errorf("invalid type: %s", ct.Type)
+}
+
+// Apply returns a copy of an item with all transformers applied in order.
+// The original item is not changed.
+func (c Chain) Apply(item *gofeed.Item) (*gofeed.Item, error) {
+	x := *item
+	for _, t := range c {
+		if err := t.Transform(&x); err != nil {
+			return nil, err
+		}
+	}
+	return &x, nil
+}
+
+// categoryPrefix prefixes a field with the categories of an item, e.g. "[Go, Linux] Title".
+type categoryPrefix struct {
+	field string
+}
+
+func (t categoryPrefix) Transform(item *gofeed.Item) error {
+	if len(item.Categories) == 0 {
+		return nil
+	}
+	v := fmt.Sprintf("[%s] %s", strings.Join(item.Categories, ", "), getField(item, t.field))
+	setField(item, t.field, v)
+	return nil
+}
+
+// regexReplace replaces all matches of a regular expression in a field.
+type regexReplace struct {
+	field   string
+	re      *regexp.Regexp
+	replace string
+}
+
+func (t regexReplace) Transform(item *gofeed.Item) error {
+	setField(item, t.field, t.re.ReplaceAllString(getField(item, t.field), t.replace))
+	return nil
+}
+
+// rewriteDomain replaces the host of a link, when it matches a domain or one of it's subdomains.
+type rewriteDomain struct {
+	from string
+	to   string
+}
+
+func (t rewriteDomain) Transform(item *gofeed.Item) error {
+	u, err := url.Parse(item.Link)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	h := strings.ToLower(u.Hostname())
+	if h != t.from && !strings.HasSuffix(h, "."+t.from) {
+		return nil
+	}
+	u.Host = t.to
+	item.Link = u.String()
+	return nil
+}
+
+// stripTracking removes tracking parameters from a link.
+type stripTracking struct{}
+
+func (t stripTracking) Transform(item *gofeed.Item) error {
+	item.Link = urls.StripTracking(item.Link)
+	return nil
+}
+
+// templateField sets a field from a template. The item is passed to the template as data.
+type templateField struct {
+	field string
+	t     *template.Template
+}
+
+func (t templateField) Transform(item *gofeed.Item) error {
+	var b strings.Builder
+	if err := t.t.Execute(&b, item); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	setField(item, t.field, b.String())
+	return nil
+}
+
+func fieldOrDefault(field, def string) string {
+	if field == "" {
+		return def
+	}
+	return field
+}
+
+func getField(item *gofeed.Item, field string) string {
+	switch field {
+	case "content":
+		return item.Content
+	case "description":
+		return item.Description
+	case "link":
+		return item.Link
+	}
+	return item.Title
+}
+
+func setField(item *gofeed.Item, field, v string) {
+	switch field {
+	case "content":
+		item.Content = v
+	case "description":
+		item.Description = v
+	case "link":
+		item.Link = v
+	default:
+		item.Title = v
+	}
+}
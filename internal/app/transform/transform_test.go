@@ -0,0 +1,102 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/transform"
+)
+
+func TestChain(t *testing.T) {
+	cases := []struct {
+		cts  []config.ConfigTransformer
+		in   gofeed.Item
+		want gofeed.Item
+	}{
+		{
+			[]config.ConfigTransformer{{Type: config.TransformRegexReplace, Pattern: `\s*\(sponsored\)`}},
+			gofeed.Item{Title: "Title (sponsored)"},
+			gofeed.Item{Title: "Title"},
+		},
+		{
+			[]config.ConfigTransformer{{Type: config.TransformRegexReplace, Field: "description", Pattern: `(\w+)@example\.com`, Replace: "$1"}},
+			gofeed.Item{Description: "by alpha@example.com"},
+			gofeed.Item{Description: "by alpha"},
+		},
+		{
+			[]config.ConfigTransformer{{Type: config.TransformStripTracking}},
+			gofeed.Item{Link: "https://www.example.com/article?id=5&utm_source=rss"},
+			gofeed.Item{Link: "https://www.example.com/article?id=5"},
+		},
+		{
+			[]config.ConfigTransformer{{Type: config.TransformRewriteDomain, From: "twitter.com", To: "nitter.net"}},
+			gofeed.Item{Link: "https://mobile.Twitter.com/user/status/1"},
+			gofeed.Item{Link: "https://nitter.net/user/status/1"},
+		},
+		{
+			[]config.ConfigTransformer{{Type: config.TransformRewriteDomain, From: "www.reddit.com", To: "old.reddit.com"}},
+			gofeed.Item{Link: "https://www.example.com/r/golang"},
+			gofeed.Item{Link: "https://www.example.com/r/golang"},
+		},
+		{
+			[]config.ConfigTransformer{{Type: config.TransformCategoryPrefix}},
+			gofeed.Item{Title: "Title", Categories: []string{"Go", "Linux"}},
+			gofeed.Item{Title: "[Go, Linux] Title", Categories: []string{"Go", "Linux"}},
+		},
+		{
+			[]config.ConfigTransformer{{Type: config.TransformCategoryPrefix}},
+			gofeed.Item{Title: "Title"},
+			gofeed.Item{Title: "Title"},
+		},
+		{
+			[]config.ConfigTransformer{{Type: config.TransformTemplate, Template: "{{.Title}} by {{.Author.Name}}"}},
+			gofeed.Item{Title: "Title", Author: &gofeed.Person{Name: "Bruce"}},
+			gofeed.Item{Title: "Title by Bruce", Author: &gofeed.Person{Name: "Bruce"}},
+		},
+		{
+			[]config.ConfigTransformer{
+				{Type: config.TransformRegexReplace, Pattern: "^Breaking: "},
+				{Type: config.TransformCategoryPrefix},
+			},
+			gofeed.Item{Title: "Breaking: Title", Categories: []string{"News"}},
+			gofeed.Item{Title: "[News] Title", Categories: []string{"News"}},
+		},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			c, err := transform.New(tc.cts)
+			if assert.NoError(t, err) {
+				got, err := c.Apply(&tc.in)
+				if assert.NoError(t, err) {
+					assert.Equal(t, tc.want, *got)
+				}
+			}
+		})
+	}
+	t.Run("should not change original item", func(t *testing.T) {
+		c, err := transform.New([]config.ConfigTransformer{{Type: config.TransformRegexReplace, Pattern: "a", Replace: "b"}})
+		if assert.NoError(t, err) {
+			item := &gofeed.Item{Title: "a"}
+			got, err := c.Apply(item)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "b", got.Title)
+				assert.Equal(t, "a", item.Title)
+			}
+		}
+	})
+	t.Run("should return error when template fails", func(t *testing.T) {
+		c, err := transform.New([]config.ConfigTransformer{{Type: config.TransformTemplate, Template: "{{.Author.Name}}"}})
+		if assert.NoError(t, err) {
+			_, err := c.Apply(&gofeed.Item{Title: "a"})
+			assert.Error(t, err)
+		}
+	})
+	t.Run("should return error when config is invalid", func(t *testing.T) {
+		_, err := transform.New([]config.ConfigTransformer{{Type: "invalid"}})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,9 @@
+package app
+
+// Translation represents the translated title and description of an item.
+type Translation struct {
+	Description string
+	SourceHash  string // hash of the original texts
+	Target      string // language code of the translation
+	Title       string
+}
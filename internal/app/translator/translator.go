@@ -0,0 +1,97 @@
+// Package translator translates texts with a LibreTranslate compatible service.
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Formats of texts
+const (
+	FormatHTML = "html"
+	FormatText = "text"
+)
+
+const maxResponseSize = 1024 * 1024
+
+// Translator translates texts with a LibreTranslate compatible service.
+// A Translator is safe for concurrent use by multiple goroutines.
+type Translator struct {
+	apiKey  string
+	client  *http.Client
+	timeout time.Duration
+	url     string
+}
+
+// New returns a new Translator for the translate endpoint at url. Requests are aborted after timeout.
+func New(client *http.Client, url, apiKey string, timeout time.Duration) *Translator {
+	t := &Translator{
+		apiKey:  apiKey,
+		client:  client,
+		timeout: timeout,
+		url:     url,
+	}
+	return t
+}
+
+type translateRequest struct {
+	APIKey string `json:"api_key,omitempty"`
+	Format string `json:"format"`
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+type translateResponse struct {
+	Error          string `json:"error"`
+	TranslatedText string `json:"translatedText"`
+}
+
+// Translate returns the translation of a text from the source to the target language.
+// The source language is detected, when it is "auto".
+func (t *Translator) Translate(text, source, target, format string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	body, err := json.Marshal(translateRequest{
+		APIKey: t.apiKey,
+		Format: format,
+		Q:      text,
+		Source: source,
+		Target: target,
+	})
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var r translateResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&r); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return "", fmt.Errorf("translate: %s", resp.Status)
+		}
+		return "", fmt.Errorf("translate: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("translate: %s: %s", resp.Status, r.Error)
+	}
+	if r.TranslatedText == "" {
+		return "", fmt.Errorf("translate: empty translation")
+	}
+	return r.TranslatedText, nil
+}
@@ -0,0 +1,60 @@
+package translator_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/app/translator"
+)
+
+func TestTranslator(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const url = "http://localhost:5000/translate"
+	t.Run("should return translated text", func(t *testing.T) {
+		httpmock.Reset()
+		var got map[string]string
+		httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+				return nil, err
+			}
+			return httpmock.NewJsonResponse(200, map[string]string{"translatedText": "Hello"})
+		})
+		tr := translator.New(http.DefaultClient, url, "key", 5*time.Second)
+		s, err := tr.Translate("Hallo", "de", "en", translator.FormatText)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Hello", s)
+			assert.Equal(t, map[string]string{"api_key": "key", "format": "text", "q": "Hallo", "source": "de", "target": "en"}, got)
+		}
+	})
+	t.Run("should not call service for empty text", func(t *testing.T) {
+		httpmock.Reset()
+		tr := translator.New(http.DefaultClient, url, "", 5*time.Second)
+		s, err := tr.Translate("", "de", "en", translator.FormatText)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "", s)
+			assert.Equal(t, 0, httpmock.GetTotalCallCount())
+		}
+	})
+	t.Run("should return error from service", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("POST", url, httpmock.NewStringResponder(400, `{"error": "xx is not supported"}`))
+		tr := translator.New(http.DefaultClient, url, "", 5*time.Second)
+		_, err := tr.Translate("Hallo", "xx", "en", translator.FormatText)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "xx is not supported")
+		}
+	})
+	t.Run("should return error when service is not available", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("POST", url, httpmock.NewStringResponder(502, "Bad Gateway"))
+		tr := translator.New(http.DefaultClient, url, "", 5*time.Second)
+		_, err := tr.Translate("Hallo", "de", "en", translator.FormatText)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,429 @@
+// Package websub implements a subscriber for WebSub push subscriptions.
+//
+// See also: https://www.w3.org/TR/websub/
+package websub
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/atom"
+
+	"github.com/ErikKalkoken/feedhook/internal/app"
+	"github.com/ErikKalkoken/feedhook/internal/app/feedparser"
+	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+)
+
+const (
+	maxContentSize = 10 * 1024 * 1024
+	renewCheckMax  = time.Minute // max time between checks for subscriptions to renew
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+// Hub represents a WebSub hub advertised by a feed.
+type Hub struct {
+	URL   string
+	Topic string // the feed's canonical URL
+}
+
+// DiscoverHub returns the WebSub hub advertised by a feed.
+// Hubs are discovered from HTTP Link headers and links in the feed itself.
+// Returns an empty hub when the feed does not advertise a hub.
+func DiscoverHub(header http.Header, body []byte, feed *gofeed.Feed) Hub {
+	var h Hub
+	for _, l := range parseLinkHeaders(header) {
+		h = h.update(l.rel, l.href)
+	}
+	if h.URL == "" {
+		if feed.FeedType == "atom" {
+			ap := atom.Parser{}
+			if f, err := ap.Parse(bytes.NewReader(body)); err == nil {
+				for _, l := range f.Links {
+					h = h.update(l.Rel, l.Href)
+				}
+			}
+		} else if ext, ok := feed.Extensions["atom"]; ok {
+			for _, l := range ext["link"] {
+				h = h.update(l.Attrs["rel"], l.Attrs["href"])
+			}
+		}
+	}
+	if h.URL == "" {
+		return Hub{}
+	}
+	if h.Topic == "" {
+		h.Topic = feed.FeedLink
+	}
+	return h
+}
+
+func (h Hub) update(rel, href string) Hub {
+	switch rel {
+	case "hub":
+		if h.URL == "" {
+			h.URL = href
+		}
+	case "self":
+		if h.Topic == "" {
+			h.Topic = href
+		}
+	}
+	return h
+}
+
+type link struct {
+	href string
+	rel  string
+}
+
+// parseLinkHeaders returns the links from all Link headers.
+func parseLinkHeaders(header http.Header) []link {
+	links := make([]link, 0)
+	for _, v := range header.Values("Link") {
+		for _, part := range strings.Split(v, ",") {
+			href, params, found := strings.Cut(strings.TrimSpace(part), ";")
+			if !found || !strings.HasPrefix(href, "<") || !strings.HasSuffix(href, ">") {
+				continue
+			}
+			for _, p := range strings.Split(params, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+				if strings.ToLower(k) != "rel" {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(v, `"`)) {
+					links = append(links, link{href: strings.Trim(href, "<>"), rel: rel})
+				}
+			}
+		}
+	}
+	return links
+}
+
+// Subscriber manages WebSub subscriptions for feeds and receives pushed content from hubs.
+type Subscriber struct {
+	callbackURL  string
+	client       *http.Client
+	clock        Clock
+	fp           *gofeed.Parser
+	leaseSeconds int
+	onContent    func(feedName string, feed *gofeed.Feed)
+	st           *storage.Storage
+}
+
+// NewSubscriber returns a new Subscriber.
+// Pushed content is parsed and handed over to onContent.
+func NewSubscriber(
+	client *http.Client,
+	st *storage.Storage,
+	clock Clock,
+	callbackURL string,
+	leaseSeconds int,
+	onContent func(feedName string, feed *gofeed.Feed),
+) *Subscriber {
+	s := &Subscriber{
+		callbackURL:  strings.TrimSuffix(callbackURL, "/"),
+		client:       client,
+		clock:        clock,
+		fp:           feedparser.New(),
+		leaseSeconds: leaseSeconds,
+		onContent:    onContent,
+		st:           st,
+	}
+	return s
+}
+
+// EnsureSubscription subscribes a feed at a hub,
+// unless it already has a subscription at that hub which does not need to be renewed.
+func (s *Subscriber) EnsureSubscription(feedName string, hub Hub) error {
+	old, err := s.st.GetSubscription(feedName)
+	if errors.Is(err, storage.ErrNotFound) {
+		old = nil
+	} else if err != nil {
+		return err
+	}
+	if old != nil && old.Hub == hub.URL && old.Topic == hub.Topic && !old.NeedsRenewal(s.clock.Now()) {
+		return nil
+	}
+	return s.subscribe(feedName, hub, old)
+}
+
+// Run renews subscriptions before their leases expire until stop is closed.
+func (s *Subscriber) Run(stop <-chan struct{}) {
+	for {
+		timer := time.NewTimer(s.RenewSubscriptions())
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RenewSubscriptions renews all subscriptions which need renewal
+// and returns the time until the next check is due.
+func (s *Subscriber) RenewSubscriptions() time.Duration {
+	subs, err := s.st.ListSubscriptions()
+	if err != nil {
+		slog.Error("Failed to read WebSub subscriptions", "error", err)
+		return renewCheckMax
+	}
+	now := s.clock.Now()
+	wait := renewCheckMax
+	for _, sub := range subs {
+		if sub.NeedsRenewal(now) {
+			if err := s.subscribe(sub.FeedName, Hub{URL: sub.Hub, Topic: sub.Topic}, sub); err != nil {
+				slog.Warn("Failed to renew WebSub subscription", "feed", sub.FeedName, "hub", sub.Hub, "error", err)
+			}
+			continue
+		}
+		wait = min(wait, sub.RenewalAt().Sub(now))
+	}
+	return max(wait, time.Second)
+}
+
+// RemoveObsolete unsubscribes and deletes the subscriptions of all feeds not in feedNames.
+func (s *Subscriber) RemoveObsolete(feedNames []string) error {
+	subs, err := s.st.ListSubscriptions()
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if slices.Contains(feedNames, sub.FeedName) {
+			continue
+		}
+		// delete first, so that the hub's verification of the unsubscribe request succeeds
+		if err := s.st.DeleteSubscription(sub.FeedName); err != nil {
+			return err
+		}
+		if err := s.unsubscribe(sub); err != nil {
+			slog.Warn("Failed to unsubscribe obsolete feed from WebSub hub", "feed", sub.FeedName, "hub", sub.Hub, "error", err)
+			continue
+		}
+		slog.Info("Removed WebSub subscription of obsolete feed", "feed", sub.FeedName, "hub", sub.Hub)
+	}
+	return nil
+}
+
+// IsActive reports wether a feed has an active subscription.
+func (s *Subscriber) IsActive(feedName string) bool {
+	sub, err := s.st.GetSubscription(feedName)
+	if err != nil {
+		return false
+	}
+	return sub.IsActive(s.clock.Now())
+}
+
+func (s *Subscriber) subscribe(feedName string, hub Hub, old *app.Subscription) error {
+	sub := &app.Subscription{
+		FeedName:    feedName,
+		Hub:         hub.URL,
+		Topic:       hub.Topic,
+		RequestedAt: s.clock.Now(),
+	}
+	if old != nil && old.Hub == hub.URL && old.Topic == hub.Topic {
+		// keep the current subscription alive until the renewal is verified
+		sub.Secret = old.Secret
+		sub.VerifiedAt = old.VerifiedAt
+		sub.ExpiresAt = old.ExpiresAt
+	} else {
+		secret, err := makeSecret()
+		if err != nil {
+			return err
+		}
+		sub.Secret = secret
+	}
+	// the hub might verify the request before it responds, so the subscription must be stored first
+	if err := s.st.SaveSubscription(sub); err != nil {
+		return err
+	}
+	data := url.Values{
+		"hub.callback":      {s.callbackFor(feedName)},
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {hub.Topic},
+		"hub.lease_seconds": {strconv.Itoa(s.leaseSeconds)},
+		"hub.secret":        {sub.Secret},
+	}
+	resp, err := s.client.PostForm(hub.URL, data)
+	if err != nil {
+		return fmt.Errorf("subscribe at hub: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("subscribe at hub: %s", resp.Status)
+	}
+	slog.Info("Requested WebSub subscription", "feed", feedName, "hub", hub.URL, "topic", hub.Topic)
+	return nil
+}
+
+func (s *Subscriber) unsubscribe(sub *app.Subscription) error {
+	data := url.Values{
+		"hub.callback": {s.callbackFor(sub.FeedName)},
+		"hub.mode":     {"unsubscribe"},
+		"hub.topic":    {sub.Topic},
+	}
+	resp, err := s.client.PostForm(sub.Hub, data)
+	if err != nil {
+		return fmt.Errorf("unsubscribe at hub: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unsubscribe at hub: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *Subscriber) callbackFor(feedName string) string {
+	return s.callbackURL + "/" + url.PathEscape(feedName)
+}
+
+// ServeHTTP handles verification requests and content distribution from hubs.
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	feedName, err := url.PathUnescape(path.Base(r.URL.EscapedPath()))
+	if err != nil {
+		http.Error(w, "invalid feed", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.handleVerification(w, r, feedName)
+	case http.MethodPost:
+		s.handleContent(w, r, feedName)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Subscriber) handleVerification(w http.ResponseWriter, r *http.Request, feedName string) {
+	q := r.URL.Query()
+	myLog := slog.With("feed", feedName, "mode", q.Get("hub.mode"))
+	sub, err := s.st.GetSubscription(feedName)
+	if errors.Is(err, storage.ErrNotFound) {
+		sub = nil
+	} else if err != nil {
+		myLog.Error("Failed to read subscription", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	switch q.Get("hub.mode") {
+	case "subscribe":
+		if sub == nil || sub.Topic != q.Get("hub.topic") {
+			myLog.Warn("Rejected verification for unknown subscription", "topic", q.Get("hub.topic"))
+			http.NotFound(w, r)
+			return
+		}
+		lease, err := strconv.Atoi(q.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			lease = s.leaseSeconds
+		}
+		now := s.clock.Now()
+		sub.VerifiedAt = now
+		sub.ExpiresAt = now.Add(time.Duration(lease) * time.Second)
+		if err := s.st.SaveSubscription(sub); err != nil {
+			myLog.Error("Failed to save subscription", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		myLog.Info("WebSub subscription verified", "expiresAt", sub.ExpiresAt)
+	case "unsubscribe":
+		if sub != nil {
+			http.NotFound(w, r)
+			return
+		}
+	case "denied":
+		if sub != nil {
+			sub.VerifiedAt = time.Time{}
+			sub.ExpiresAt = time.Time{}
+			if err := s.st.SaveSubscription(sub); err != nil {
+				myLog.Error("Failed to save subscription", "error", err)
+			}
+		}
+		myLog.Warn("WebSub subscription denied by hub", "reason", q.Get("hub.reason"))
+		return
+	default:
+		http.Error(w, "invalid mode", http.StatusBadRequest)
+		return
+	}
+	io.WriteString(w, q.Get("hub.challenge"))
+}
+
+func (s *Subscriber) handleContent(w http.ResponseWriter, r *http.Request, feedName string) {
+	myLog := slog.With("feed", feedName)
+	sub, err := s.st.GetSubscription(feedName)
+	if err != nil {
+		http.Error(w, "unknown subscription", http.StatusGone)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxContentSize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	// Hubs expect a success response even when the signature is invalid
+	w.WriteHeader(http.StatusAccepted)
+	if !isValidSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		myLog.Warn("Ignoring pushed content with invalid signature")
+		return
+	}
+	feed, err := s.fp.Parse(bytes.NewReader(body))
+	if err != nil {
+		myLog.Warn("Failed to parse pushed content", "error", err)
+		return
+	}
+	go s.onContent(feedName, feed)
+}
+
+// isValidSignature reports wether a signature from a X-Hub-Signature header is valid.
+func isValidSignature(secret, signature string, body []byte) bool {
+	method, sig, found := strings.Cut(signature, "=")
+	if !found {
+		return false
+	}
+	var h func() hash.Hash
+	switch method {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func makeSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,299 @@
+package websub_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ErikKalkoken/feedhook/internal/app"
+	"github.com/ErikKalkoken/feedhook/internal/app/config"
+	"github.com/ErikKalkoken/feedhook/internal/app/messenger"
+	"github.com/ErikKalkoken/feedhook/internal/app/storage"
+	"github.com/ErikKalkoken/feedhook/internal/app/websub"
+)
+
+type fakeTime struct {
+	now time.Time
+}
+
+func (rt fakeTime) Now() time.Time {
+	return rt.now
+}
+
+const atomFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example</title>
+  <link rel="hub" href="https://hub.example.com/"/>
+  <link rel="self" href="https://www.example.com/feed.atom"/>
+  <entry>
+    <id>1</id>
+    <title>Item 1</title>
+    <content>content</content>
+    <updated>2024-08-22T04:33:37Z</updated>
+  </entry>
+</feed>`
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Example</title>
+    <atom:link rel="hub" href="https://hub.example.com/"/>
+    <atom:link rel="self" href="https://www.example.com/feed.rss"/>
+  </channel>
+</rss>`
+
+func TestDiscoverHub(t *testing.T) {
+	fp := gofeed.NewParser()
+	t.Run("can discover hub from link headers", func(t *testing.T) {
+		feed, err := fp.ParseString(rssFeed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := http.Header{}
+		h.Add("Link", `<https://hub2.example.com/>; rel="hub", <https://www.example.com/topic>; rel="self"`)
+		got := websub.DiscoverHub(h, []byte(rssFeed), feed)
+		assert.Equal(t, websub.Hub{URL: "https://hub2.example.com/", Topic: "https://www.example.com/topic"}, got)
+	})
+	t.Run("can discover hub from atom feed", func(t *testing.T) {
+		feed, err := fp.ParseString(atomFeed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := websub.DiscoverHub(http.Header{}, []byte(atomFeed), feed)
+		assert.Equal(t, websub.Hub{URL: "https://hub.example.com/", Topic: "https://www.example.com/feed.atom"}, got)
+	})
+	t.Run("can discover hub from rss feed", func(t *testing.T) {
+		feed, err := fp.ParseString(rssFeed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := websub.DiscoverHub(http.Header{}, []byte(rssFeed), feed)
+		assert.Equal(t, websub.Hub{URL: "https://hub.example.com/", Topic: "https://www.example.com/feed.rss"}, got)
+	})
+	t.Run("should return empty hub when feed has none", func(t *testing.T) {
+		s := `<rss version="2.0"><channel><title>Example</title></channel></rss>`
+		feed, err := fp.ParseString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := websub.DiscoverHub(http.Header{}, []byte(s), feed)
+		assert.Equal(t, websub.Hub{}, got)
+	})
+}
+
+func TestSubscriber(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	st := storage.New(db, config.Config{})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+	hub := websub.Hub{URL: "https://hub.example.com/", Topic: "https://www.example.com/feed.atom"}
+	received := make(chan *gofeed.Feed, 10)
+	s := websub.NewSubscriber(&http.Client{}, st, fakeTime{now: now}, "https://feedhook.example.com/websub", 3600, func(feedName string, feed *gofeed.Feed) {
+		received <- feed
+	})
+	t.Run("can request a subscription", func(t *testing.T) {
+		httpmock.Reset()
+		var form url.Values
+		httpmock.RegisterResponder("POST", hub.URL, func(req *http.Request) (*http.Response, error) {
+			req.ParseForm()
+			form = req.PostForm
+			return httpmock.NewStringResponse(202, ""), nil
+		})
+		err := s.EnsureSubscription("feed 1", hub)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "subscribe", form.Get("hub.mode"))
+			assert.Equal(t, hub.Topic, form.Get("hub.topic"))
+			assert.Equal(t, "https://feedhook.example.com/websub/feed%201", form.Get("hub.callback"))
+			assert.False(t, s.IsActive("feed 1"))
+		}
+	})
+	t.Run("should reject verification for unknown topic", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/websub/feed%201?hub.mode=subscribe&hub.topic=other&hub.challenge=abc", nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.False(t, s.IsActive("feed 1"))
+	})
+	t.Run("can verify a subscription", func(t *testing.T) {
+		q := url.Values{
+			"hub.mode":          {"subscribe"},
+			"hub.topic":         {hub.Topic},
+			"hub.challenge":     {"abc"},
+			"hub.lease_seconds": {"600"},
+		}
+		r := httptest.NewRequest("GET", "/websub/feed%201?"+q.Encode(), nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "abc", w.Body.String())
+		assert.True(t, s.IsActive("feed 1"))
+	})
+	t.Run("should not request subscription again when active", func(t *testing.T) {
+		httpmock.Reset()
+		err := s.EnsureSubscription("feed 1", hub)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, httpmock.GetTotalCallCount())
+		}
+	})
+	t.Run("should ignore pushed content with invalid signature", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/websub/feed%201", strings.NewReader(atomFeed))
+		r.Header.Set("X-Hub-Signature", "sha256=invalid")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		select {
+		case <-received:
+			t.Fatal("content was not ignored")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+	t.Run("can receive pushed content", func(t *testing.T) {
+		sub, err := st.GetSubscription("feed 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mac := hmac.New(sha256.New, []byte(sub.Secret))
+		mac.Write([]byte(atomFeed))
+		r := httptest.NewRequest("POST", "/websub/feed%201", strings.NewReader(atomFeed))
+		r.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		select {
+		case feed := <-received:
+			assert.Equal(t, "Item 1", feed.Items[0].Title)
+		case <-time.After(time.Second):
+			t.Fatal("content not received")
+		}
+	})
+	t.Run("should keep comments URL of pushed items", func(t *testing.T) {
+		sub, err := st.GetSubscription("feed 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		const rss = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Example</title>
+<item><title>Item 1</title><link>https://www.example.com/1</link><comments>https://www.example.com/1#comments</comments></item>
+</channel></rss>`
+		mac := hmac.New(sha256.New, []byte(sub.Secret))
+		mac.Write([]byte(rss))
+		r := httptest.NewRequest("POST", "/websub/feed%201", strings.NewReader(rss))
+		r.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		select {
+		case feed := <-received:
+			assert.Equal(t, "https://www.example.com/1#comments", feed.Items[0].Custom[messenger.CustomComments])
+		case <-time.After(time.Second):
+			t.Fatal("content not received")
+		}
+	})
+}
+
+func TestRenewSubscriptions(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	st := storage.New(db, config.Config{})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("POST", "https://hub.example.com/", httpmock.NewStringResponder(202, ""))
+	verified := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+	sub := &app.Subscription{
+		FeedName:    "feed1",
+		Hub:         "https://hub.example.com/",
+		Topic:       "https://www.example.com/feed.atom",
+		Secret:      "secret",
+		RequestedAt: verified,
+		VerifiedAt:  verified,
+		ExpiresAt:   verified.Add(300 * time.Second),
+	}
+	if err := st.SaveSubscription(sub); err != nil {
+		t.Fatal(err)
+	}
+	newSubscriber := func(now time.Time) *websub.Subscriber {
+		return websub.NewSubscriber(&http.Client{}, st, fakeTime{now: now}, "https://feedhook.example.com/websub", 300, nil)
+	}
+	t.Run("should schedule renewal from lease", func(t *testing.T) {
+		httpmock.ZeroCallCounters()
+		wait := newSubscriber(verified.Add(250 * time.Second)).RenewSubscriptions()
+		assert.Equal(t, 20*time.Second, wait)
+		assert.Equal(t, 0, httpmock.GetTotalCallCount())
+	})
+	t.Run("should renew subscription before lease expires", func(t *testing.T) {
+		httpmock.ZeroCallCounters()
+		now := verified.Add(280 * time.Second)
+		newSubscriber(now).RenewSubscriptions()
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+		got, err := st.GetSubscription("feed1")
+		if assert.NoError(t, err) {
+			assert.Equal(t, now, got.RequestedAt)
+			assert.Equal(t, "secret", got.Secret)
+		}
+	})
+}
+
+func TestRemoveObsolete(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %s", err)
+	}
+	defer db.Close()
+	st := storage.New(db, config.Config{})
+	if err := st.Init(); err != nil {
+		t.Fatalf("Failed to init: %s", err)
+	}
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	var form url.Values
+	httpmock.RegisterResponder("POST", "https://hub.example.com/", func(req *http.Request) (*http.Response, error) {
+		req.ParseForm()
+		form = req.PostForm
+		return httpmock.NewStringResponse(202, ""), nil
+	})
+	for _, n := range []string{"feed1", "feed2"} {
+		sub := &app.Subscription{FeedName: n, Hub: "https://hub.example.com/", Topic: "https://www.example.com/" + n}
+		if err := st.SaveSubscription(sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := websub.NewSubscriber(&http.Client{}, st, fakeTime{now: time.Now()}, "https://feedhook.example.com/websub", 3600, nil)
+	err = s.RemoveObsolete([]string{"feed1"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "unsubscribe", form.Get("hub.mode"))
+		assert.Equal(t, "https://www.example.com/feed2", form.Get("hub.topic"))
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+		_, err := st.GetSubscription("feed2")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		_, err = st.GetSubscription("feed1")
+		assert.NoError(t, err)
+	}
+}
@@ -11,7 +11,10 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
-var ErrEmpty = errors.New("empty queue")
+var (
+	ErrEmpty  = errors.New("empty queue")
+	ErrNoRoom = errors.New("no room at front of queue")
+)
 
 // PQueue represents a persistent FIFO queue.
 type PQueue struct {
@@ -131,6 +134,31 @@ func (q *PQueue) Put(v []byte) error {
 	return nil
 }
 
+// PutFront adds an item to the front of the queue, e.g. to return an item which could not be processed.
+func (q *PQueue) PutFront(v []byte) error {
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(q.name))
+		k, _ := b.Cursor().First()
+		if k == nil {
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			return b.Put(itob(id), v)
+		}
+		id := binary.BigEndian.Uint64(k)
+		if id == 0 {
+			return ErrNoRoom
+		}
+		return b.Put(itob(id-1), v)
+	})
+	if err != nil {
+		return err
+	}
+	q.cond.Signal()
+	return nil
+}
+
 func itob(v uint64) []byte {
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, uint64(v))
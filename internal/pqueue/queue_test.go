@@ -57,6 +57,45 @@ func TestQueue(t *testing.T) {
 			}
 		}
 	})
+	t.Run("should return item put to the front first", func(t *testing.T) {
+		if err := q.Clear(); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Put([]byte("alpha")); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Put([]byte("bravo")); err != nil {
+			t.Fatal(err)
+		}
+		v, err := q.GetNoWait()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.PutFront(v); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for !q.IsEmpty() {
+			v, err := q.GetNoWait()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, string(v))
+		}
+		assert.Equal(t, []string{"alpha", "bravo"}, got)
+	})
+	t.Run("can put to the front of an empty queue", func(t *testing.T) {
+		if err := q.Clear(); err != nil {
+			t.Fatal(err)
+		}
+		err := q.PutFront([]byte("alpha"))
+		if assert.NoError(t, err) {
+			v, err := q.GetNoWait()
+			if assert.NoError(t, err) {
+				assert.Equal(t, []byte("alpha"), v)
+			}
+		}
+	})
 	t.Run("should report queue size", func(t *testing.T) {
 		if err := q.Clear(); err != nil {
 			t.Fatal(err)
@@ -38,6 +38,19 @@ func (sm *SyncedMap[K, V]) Store(key K, value V) {
 	sm.m[key] = value
 }
 
+// LoadOrStore returns the existing value for the key if present.
+// Otherwise, it stores and returns the given value.
+// The loaded result is true if the value was loaded, false if stored.
+func (sm *SyncedMap[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if v, ok := sm.m[key]; ok {
+		return v, true
+	}
+	sm.m[key] = value
+	return value, false
+}
+
 // Clone returns a snapshot of the map
 func (sm *SyncedMap[K, V]) Clone() map[K]V {
 	sm.mu.RLock()
@@ -24,6 +24,22 @@ func TestMap(t *testing.T) {
 		_, ok := m.Load("bravo")
 		assert.False(t, ok)
 	})
+	t.Run("can store value when key does not exist", func(t *testing.T) {
+		m := syncedmap.New[string, int]()
+		v, loaded := m.LoadOrStore("alpha", 1)
+		assert.False(t, loaded)
+		assert.Equal(t, 1, v)
+		v, ok := m.Load("alpha")
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+	})
+	t.Run("can load existing value instead of storing", func(t *testing.T) {
+		m := syncedmap.New[string, int]()
+		m.Store("alpha", 1)
+		v, loaded := m.LoadOrStore("alpha", 2)
+		assert.True(t, loaded)
+		assert.Equal(t, 1, v)
+	})
 	t.Run("should work concurrently", func(t *testing.T) {
 		values := []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf"}
 		m := syncedmap.New[string, int]()
@@ -0,0 +1,72 @@
+// Package urls provides helpers for working with URLs.
+package urls
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters used for tracking, which do not identify content.
+var trackingParams = map[string]bool{
+	"_ga":     true,
+	"fbclid":  true,
+	"gclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"msclkid": true,
+	"ref_src": true,
+	"yclid":   true,
+}
+
+// IsTrackingParam reports wether a query parameter is used for tracking.
+func IsTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || trackingParams[name]
+}
+
+// Normalize returns a normalized form of a link, so that links to the same content are equal.
+// Tracking parameters and fragments are removed, query parameters sorted
+// and scheme and host lower cased. Returns the link unchanged when it is not a valid URL.
+func Normalize(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	q := u.Query()
+	for k := range q {
+		if IsTrackingParam(k) {
+			q.Del(k)
+		}
+	}
+	u.RawQuery = q.Encode() // also sorts by key
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+// StripTracking returns a link without tracking parameters.
+// Other parts of the link are kept as is. Returns the link unchanged when it is not a valid URL.
+func StripTracking(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.RawQuery == "" {
+		return link
+	}
+	parts := strings.Split(u.RawQuery, "&")
+	kept := parts[:0]
+	for _, p := range parts {
+		name, _, _ := strings.Cut(p, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if !IsTrackingParam(name) {
+			kept = append(kept, p)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String()
+}
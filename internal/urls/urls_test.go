@@ -0,0 +1,67 @@
+package urls_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikKalkoken/feedhook/internal/urls"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"https://www.example.com/article", "https://www.example.com/article"},
+		{"HTTPS://WWW.Example.com/article/", "https://www.example.com/article"},
+		{"https://www.example.com/article?utm_source=rss&utm_medium=feed", "https://www.example.com/article"},
+		{"https://www.example.com/article?id=5&fbclid=abc&a=1", "https://www.example.com/article?a=1&id=5"},
+		{"https://www.example.com/article#comments", "https://www.example.com/article"},
+		{"https://github.com/owner/repo/blob/main/go.mod?ref=dev", "https://github.com/owner/repo/blob/main/go.mod?ref=dev"},
+		{"invalid", "invalid"},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, urls.Normalize(tc.in))
+		})
+	}
+}
+
+func TestIsTrackingParam(t *testing.T) {
+	cases := []struct {
+		in   string
+		want bool
+	}{
+		{"utm_source", true},
+		{"UTM_Campaign", true},
+		{"fbclid", true},
+		{"id", false},
+		{"page", false},
+		{"ref", false},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, urls.IsTrackingParam(tc.in))
+		})
+	}
+}
+
+func TestStripTracking(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"https://www.example.com/article", "https://www.example.com/article"},
+		{"https://www.example.com/article?utm_source=rss&utm_medium=feed", "https://www.example.com/article"},
+		{"https://www.example.com/article?id=5&fbclid=abc&a=1", "https://www.example.com/article?id=5&a=1"},
+		{"https://www.example.com/Article/?utm_source=rss#comments", "https://www.example.com/Article/#comments"},
+		{"invalid", "invalid"},
+	}
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("#%d", i+1), func(t *testing.T) {
+			assert.Equal(t, tc.want, urls.StripTracking(tc.in))
+		})
+	}
+}